@@ -66,7 +66,7 @@ var onsuccess = function(request) {
 
   scaleboxes()
 };
-setInterval(function() {
+var poll = function() {
   var request = new XMLHttpRequest();
   request.open('GET', location.href, true);
   request.onload = function() {
@@ -78,10 +78,83 @@ setInterval(function() {
   };
   request.onerror = onerror;
   request.send();
+};
+
+// host and group pages are updated tile by tile from server-sent events when the browser
+// supports them, and other pages are re-fetched every refresh_interval seconds
+var events = location.pathname.match(/^\/(host|group)\/[^\/]+$/);
+var live = false;
+
+// tiles returns the element holding the tiles of a host
+var tiles = function(host) {
+  if (location.pathname.indexOf('/host/') === 0) {
+    return document.querySelector('.scalable');
+  }
+  var links = document.querySelectorAll('.group > a');
+  for (var i = 0; i < links.length; i++) {
+    if (links[i].textContent === host) {
+      return links[i].nextElementSibling;
+    }
+  }
+};
+
+var listen = function() {
+  var source = new EventSource('/events' + location.pathname + location.search);
+  source.onopen = function() {
+    live = true;
+    var el = document.getElementById('countdown');
+    if (el) {
+      el.innerText = 'live';
+    }
+  };
+  source.addEventListener('host', function(e) {
+    var event = JSON.parse(e.data);
+    var el = tiles(event.host);
+    if (el) {
+      el.innerHTML = event.html;
+      scaleboxes();
+    }
+  });
+  source.addEventListener('tile', function(e) {
+    var event = JSON.parse(e.data);
+    var el = tiles(event.host);
+    if (!el) {
+      return;
+    }
+    var boxes = el.querySelectorAll('a.outer');
+    for (var i = 0; i < boxes.length; i++) {
+      if (boxes[i].getAttribute('data-key') === event.key) {
+        boxes[i].outerHTML = event.html;
+      }
+    }
+    scaleboxes();
+  });
+  source.addEventListener('updated', function(e) {
+    var el = document.querySelector('.time .updated');
+    if (el) {
+      el.innerText = 'updated ' + JSON.parse(e.data).updated;
+    }
+  });
+  source.onerror = function() {
+    live = false;
+    var el = document.getElementById('countdown');
+    if (el) {
+      el.innerText = refresh_interval;
+    }
+  };
+};
+
+if (events && window.EventSource) {
+  window.addEventListener("load", listen);
+}
+setInterval(function() {
+  if (!live) {
+    poll();
+  }
 }, refresh_interval * 1000);
 setInterval(function() {
   var el = document.getElementById('countdown');
-  if(el) {
+  if(el && !live) {
     var counter = parseInt(el.innerText, 10);
     el.innerText = counter - 1;
   }
@@ -0,0 +1,90 @@
+package summary
+
+import (
+	"encoding/json"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/concourse/atc"
+)
+
+// InstanceVars are the variables that distinguish the instances of an instanced pipeline
+type InstanceVars map[string]interface{}
+
+// pipelineInstance is a pipeline as listed by the concourse API, including the instance vars and
+// archived state of newer concourse versions
+type pipelineInstance struct {
+	atc.Pipeline
+	InstanceVars InstanceVars `json:"instance_vars,omitempty"`
+	Archived     bool         `json:"archived"`
+}
+
+// query returns the query parameters that identify a pipeline instance to concourse
+func (v InstanceVars) query() url.Values {
+	if len(v) == 0 {
+		return nil
+	}
+	return url.Values{"vars": []string{v.String()}}
+}
+
+// String returns the instance vars as JSON, with keys sorted so that it identifies an instance,
+// or a blank string when there are none
+func (v InstanceVars) String() string {
+	if len(v) == 0 {
+		return ""
+	}
+	encoded, err := json.Marshal(map[string]interface{}(v))
+	if err != nil {
+		return ""
+	}
+	return string(encoded)
+}
+
+// normalInstanceVars re-encodes instance vars given as JSON so that they can be compared with
+// InstanceVars.String
+func normalInstanceVars(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	var vars InstanceVars
+	if err := json.Unmarshal([]byte(value), &vars); err != nil {
+		return "", err
+	}
+	return vars.String(), nil
+}
+
+// Label describes the instance vars for display, eg, branch: main, env: prod
+func (v InstanceVars) Label() string {
+	keys := make([]string, 0, len(v))
+	for key := range v {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		value, ok := v[key].(string)
+		if !ok {
+			encoded, _ := json.Marshal(v[key])
+			value = string(encoded)
+		}
+		parts = append(parts, key+": "+value)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// contains reports whether every var in selected has the same value in v
+func (v InstanceVars) contains(selected InstanceVars) bool {
+	for key, value := range selected {
+		actual, ok := v[key]
+		if !ok {
+			return false
+		}
+		expected, _ := json.Marshal(value)
+		encoded, _ := json.Marshal(actual)
+		if string(expected) != string(encoded) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,160 @@
+package summary_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/FidelityInternational/go-concourse-summary/concourse"
+)
+
+var _ = Describe("JSON API", func() {
+	var (
+		mockRecorder    *httptest.ResponseRecorder
+		config          *summary.Config
+		path            string
+		status          int
+		allowAdHocHosts bool
+	)
+
+	BeforeEach(func() {
+		status = 200
+		allowAdHocHosts = true
+	})
+
+	JustBeforeEach(func() {
+		mocks := []MockRoute{
+			{"GET", "/api/v1/teams/main/pipelines", pipelinesPayload, status, "", nil},
+			{"GET", "/api/v1/teams/main/pipelines/test1/jobs", jobsPayload, 200, "", nil},
+			{"GET", "/api/v1/teams/main/pipelines/test1/resources", "[]", 200, "", nil},
+		}
+		setupMultiple(mocks)
+
+		config = buildConfig(nil, "main", "http")
+		config.AllowAdHocHosts = allowAdHocHosts
+		config.CSGroups = summary.CSGroups{
+			{
+				Group: "test",
+				Hosts: []summary.Host{
+					{
+						FQDN:      Host(server),
+						Pipelines: []summary.Pipeline{{Name: "test1"}},
+					},
+				},
+			},
+		}
+
+		router := Router(config)
+		config.Collector.Collect(summary.Host{FQDN: Host(server)})
+		mockRecorder = httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "http://example.com"+strings.Replace(path, "HOST", Host(server), 1), nil)
+		router.ServeHTTP(mockRecorder, req)
+	})
+
+	AfterEach(func() {
+		config.Collector.Stop()
+		teardown()
+	})
+
+	Describe("/api/v1/host/{host}", func() {
+		BeforeEach(func() {
+			path = "/api/v1/host/HOST"
+		})
+
+		It("returns the host data as JSON", func() {
+			Ω(mockRecorder.Code).Should(Equal(200))
+			Ω(mockRecorder.Header().Get("Content-Type")).Should(Equal("application/json"))
+			Ω(mockRecorder.Header().Get("Last-Modified")).ShouldNot(BeEmpty())
+			Ω(stripHostPort(mockRecorder.Body.String())).Should(MatchJSON(`[
+				{
+					"team": "main",
+					"pipeline": "test1",
+					"group": "",
+					"pipeline_url": "http://127.0.0.1:pppp/teams/main/pipelines/test1",
+					"running": false,
+					"paused": false,
+					"broken_resource": false,
+					"statuses": {"aborted": 1, "errored": 1, "failed": 1, "pending": 1, "started": 1, "succeeded": 1},
+					"percentages": {"aborted": 16, "errored": 16, "failed": 16, "pending": 16, "started": 16, "succeeded": 16}
+				}
+			]`))
+		})
+
+		Context("when ad hoc hosts are not allowed and the host is not configured", func() {
+			BeforeEach(func() {
+				path = "/api/v1/host/unknown.example.com"
+				allowAdHocHosts = false
+			})
+
+			It("returns a not found error", func() {
+				Ω(mockRecorder.Code).Should(Equal(404))
+				Ω(mockRecorder.Body.String()).Should(MatchJSON(`{"error": "host unknown.example.com is not configured"}`))
+			})
+		})
+
+		Context("when collecting from concourse failed", func() {
+			BeforeEach(func() {
+				status = 500
+			})
+
+			It("returns the error as JSON", func() {
+				Ω(mockRecorder.Code).Should(Equal(500))
+				Ω(mockRecorder.Body.String()).Should(ContainSubstring(`"error":"Unexpected Response`))
+			})
+		})
+	})
+
+	Describe("/api/v1/group/{group}", func() {
+		BeforeEach(func() {
+			path = "/api/v1/group/test"
+		})
+
+		It("returns the group data as JSON", func() {
+			Ω(mockRecorder.Code).Should(Equal(200))
+			Ω(mockRecorder.Header().Get("Content-Type")).Should(Equal("application/json"))
+			Ω(stripHostPort(mockRecorder.Body.String())).Should(MatchJSON(`[
+				{
+					"host": "127.0.0.1:pppp",
+					"statuses": [
+						{
+							"team": "main",
+							"pipeline": "test1",
+							"group": "",
+							"pipeline_url": "http://127.0.0.1:pppp/teams/main/pipelines/test1",
+							"running": false,
+							"paused": false,
+							"broken_resource": false,
+							"statuses": {"aborted": 1, "errored": 1, "failed": 1, "pending": 1, "started": 1, "succeeded": 1},
+							"percentages": {"aborted": 16, "errored": 16, "failed": 16, "pending": 16, "started": 16, "succeeded": 16}
+						}
+					]
+				}
+			]`))
+		})
+
+		Context("when collecting from concourse failed", func() {
+			BeforeEach(func() {
+				status = 500
+			})
+
+			It("returns the host with its error", func() {
+				Ω(mockRecorder.Code).Should(Equal(200))
+				Ω(mockRecorder.Body.String()).Should(ContainSubstring(`"statuses":null,"error":"Unexpected Response`))
+			})
+		})
+
+		Context("when the group is not configured", func() {
+			BeforeEach(func() {
+				path = "/api/v1/group/unknown"
+			})
+
+			It("returns a not found error", func() {
+				Ω(mockRecorder.Code).Should(Equal(404))
+				Ω(mockRecorder.Body.String()).Should(MatchJSON(`{"error": "group unknown is not configured"}`))
+			})
+		})
+	})
+})
@@ -0,0 +1,224 @@
+package summary_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/gorilla/mux"
+
+	"github.com/FidelityInternational/go-concourse-summary/concourse"
+)
+
+const versionedJobsPayload = `[
+  {
+    "id": 1,
+    "name": "build",
+    "pipeline_name": "app",
+    "team_name": "main",
+    "inputs": [{"name": "repo", "resource": "repo", "trigger": true}],
+    "finished_build": {"id": 1, "name": "7", "status": "succeeded", "team_name": "main", "pipeline_name": "app", "job_name": "build"}
+  }
+]`
+
+// fixtures for each generation of the concourse API, keyed by the version that they were taken from
+var versionedFixtures = map[string]struct {
+	pipelines string
+	resources string
+}{
+	"3.14.1": {
+		pipelines: `[{"id": 1, "name": "app", "paused": false, "public": false, "team_name": "main"}]`,
+		resources: `[{"name": "repo", "pipeline_name": "app", "team_name": "main", "type": "git", "failing_to_check": true, "check_error": "git clone failed"}]`,
+	},
+	"6.7.2": {
+		pipelines: `[{"id": 1, "name": "app", "paused": false, "public": false, "archived": false, "team_name": "main", "last_updated": 1600000000}]`,
+		resources: `[{"name": "repo", "pipeline_name": "app", "team_name": "main", "type": "git", "last_checked": 1600000000, "check_setup_error": "git clone failed"}]`,
+	},
+	"7.4.0": {
+		pipelines: `[
+  {"id": 1, "name": "app", "paused": false, "public": false, "archived": false, "team_name": "main", "last_updated": 1600000000},
+  {"id": 2, "name": "old-app", "paused": true, "public": false, "archived": true, "team_name": "main", "last_updated": 1500000000}
+]`,
+		resources: `[{"name": "repo", "pipeline_name": "app", "team_name": "main", "type": "git", "last_checked": 1600000000, "build": {"id": 9, "name": "9", "status": "failed"}}]`,
+	},
+}
+
+var _ = Describe("Collector#Collect against each concourse API version", func() {
+	var (
+		version        string
+		apiServer      *httptest.Server
+		tokenPaths     []string
+		tokenForms     []string
+		tokenClients   []string
+		pipelineHeads  []string
+		auth           *summary.Auth
+		snapshot       summary.Snapshot
+		authorization  string
+		issuerEndpoint bool
+	)
+
+	BeforeEach(func() {
+		tokenPaths = nil
+		tokenForms = nil
+		tokenClients = nil
+		pipelineHeads = nil
+		auth = &summary.Auth{Username: "user", Password: "pass"}
+	})
+
+	AfterEach(func() {
+		apiServer.Close()
+	})
+
+	JustBeforeEach(func() {
+		fixture := versionedFixtures[version]
+		router := mux.NewRouter()
+		router.HandleFunc("/api/v1/info", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `{"version": "%s", "worker_version": "2.2"}`, version)
+		})
+		router.HandleFunc("/api/v1/teams/main/auth/token", func(w http.ResponseWriter, r *http.Request) {
+			tokenPaths = append(tokenPaths, r.URL.Path)
+			fmt.Fprint(w, `{"type": "Bearer", "value": "legacy-token"}`)
+		})
+		skyToken := func(w http.ResponseWriter, r *http.Request) {
+			tokenPaths = append(tokenPaths, r.URL.Path)
+			Ω(r.ParseForm()).Should(Succeed())
+			tokenForms = append(tokenForms, fmt.Sprintf("%s %s %s", r.PostForm.Get("grant_type"), r.PostForm.Get("username"), r.PostForm.Get("password")))
+			tokenClients = append(tokenClients, r.Header.Get("Authorization"))
+			fmt.Fprint(w, `{"token_type": "bearer", "access_token": "access-token", "id_token": "id-token"}`)
+		}
+		if issuerEndpoint {
+			router.HandleFunc("/sky/issuer/token", skyToken).Methods("POST")
+		} else {
+			router.HandleFunc("/sky/token", skyToken).Methods("POST")
+		}
+		router.HandleFunc("/api/v1/teams/main/pipelines", func(w http.ResponseWriter, r *http.Request) {
+			pipelineHeads = append(pipelineHeads, r.Header.Get("Authorization"))
+			if r.Header.Get("Authorization") != authorization {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, fixture.pipelines)
+		})
+		router.HandleFunc("/api/v1/teams/main/pipelines/{pipeline}/jobs", func(w http.ResponseWriter, r *http.Request) {
+			Ω(mux.Vars(r)["pipeline"]).Should(Equal("app"))
+			fmt.Fprint(w, versionedJobsPayload)
+		})
+		router.HandleFunc("/api/v1/teams/main/pipelines/{pipeline}/resources", func(w http.ResponseWriter, r *http.Request) {
+			Ω(mux.Vars(r)["pipeline"]).Should(Equal("app"))
+			fmt.Fprint(w, fixture.resources)
+		})
+		apiServer = httptest.NewServer(router)
+
+		config := buildConfig(nil, "main", "http")
+		snapshot = summary.NewCollector(config).Collect(summary.Host{URL: apiServer.URL, Auth: auth})
+	})
+
+	itCollectsThePipeline := func(checkError string) {
+		It("collects the pipeline and its broken resources", func() {
+			Ω(snapshot.Err).Should(BeNil())
+			Ω(snapshot.Data).Should(HaveLen(1))
+			Ω(snapshot.Data[0].Pipeline).Should(Equal("app"))
+			Ω(snapshot.Data[0].Statuses["succeeded"]).Should(Equal(1))
+			Ω(snapshot.Data[0].BrokenResources).Should(Equal([]summary.BrokenResource{{Name: "repo", CheckError: checkError}}))
+		})
+	}
+
+	Context("when the host runs concourse 3", func() {
+		BeforeEach(func() {
+			version = "3.14.1"
+			authorization = "Bearer legacy-token"
+			issuerEndpoint = false
+		})
+
+		itCollectsThePipeline("git clone failed")
+
+		It("acquires a team token", func() {
+			Ω(tokenPaths).Should(Equal([]string{"/api/v1/teams/main/auth/token"}))
+			Ω(pipelineHeads).Should(Equal([]string{"Bearer legacy-token"}))
+		})
+	})
+
+	Context("when the host runs concourse 6", func() {
+		BeforeEach(func() {
+			version = "6.7.2"
+			authorization = "Bearer access-token"
+			issuerEndpoint = false
+		})
+
+		itCollectsThePipeline("git clone failed")
+
+		It("falls back to the sky token endpoint for the password grant", func() {
+			Ω(tokenPaths).Should(Equal([]string{"/sky/token"}))
+			Ω(tokenForms).Should(Equal([]string{"password user pass"}))
+			Ω(tokenClients).Should(Equal([]string{"Basic Zmx5OlpteDU="}))
+		})
+
+		It("authenticates with the access token", func() {
+			Ω(pipelineHeads).Should(Equal([]string{"Bearer access-token"}))
+		})
+	})
+
+	Context("when the host runs concourse 7", func() {
+		BeforeEach(func() {
+			version = "7.4.0"
+			authorization = "Bearer id-token"
+			issuerEndpoint = true
+		})
+
+		itCollectsThePipeline("check failed")
+
+		It("uses the sky issuer token endpoint", func() {
+			Ω(tokenPaths).Should(Equal([]string{"/sky/issuer/token"}))
+			Ω(tokenForms).Should(Equal([]string{"password user pass"}))
+			Ω(pipelineHeads).Should(Equal([]string{"Bearer id-token"}))
+		})
+
+		Context("and the host has a bearer token", func() {
+			BeforeEach(func() {
+				auth = &summary.Auth{Token: "my-token"}
+				authorization = "Bearer my-token"
+			})
+
+			It("uses the token as it is", func() {
+				Ω(snapshot.Err).Should(BeNil())
+				Ω(tokenPaths).Should(BeEmpty())
+				Ω(pipelineHeads).Should(Equal([]string{"Bearer my-token"}))
+			})
+		})
+	})
+})
+
+var _ = Describe("Collector#Collect when the version of a host cannot be fetched", func() {
+	var (
+		apiServer    *httptest.Server
+		infoRequests int
+	)
+
+	BeforeEach(func() {
+		infoRequests = 0
+		router := mux.NewRouter()
+		router.HandleFunc("/api/v1/info", func(w http.ResponseWriter, r *http.Request) {
+			infoRequests++
+			w.WriteHeader(http.StatusInternalServerError)
+		})
+		router.HandleFunc("/api/v1/teams/main/pipelines", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "[]")
+		})
+		apiServer = httptest.NewServer(router)
+	})
+
+	AfterEach(func() {
+		apiServer.Close()
+	})
+
+	It("does not try to fetch it again on every collection", func() {
+		collector := summary.NewCollector(buildConfig(nil, "main", "http"))
+		host := summary.Host{URL: apiServer.URL}
+		Ω(collector.Collect(host).Err).Should(BeNil())
+		Ω(collector.Collect(host).Err).Should(BeNil())
+		Ω(infoRequests).Should(Equal(1))
+	})
+})
@@ -0,0 +1,219 @@
+package summary
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/concourse/atc"
+)
+
+var (
+	defaultTokenTTL   = time.Hour
+	tokenExpiryLeeway = time.Minute
+	tokens            = &tokenCache{tokens: map[string]cachedToken{}}
+	defaultTokenType  = "Bearer"
+)
+
+// Auth holds the credentials used to acquire a team token from a concourse host
+type Auth struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Token    string `json:"token"`
+}
+
+// header returns the Authorization header used when requesting a team token
+func (a *Auth) header() string {
+	if a.Token != "" {
+		return "Bearer " + a.Token
+	}
+	credentials := base64.StdEncoding.EncodeToString([]byte(a.Username + ":" + a.Password))
+	return "Basic " + credentials
+}
+
+type cachedToken struct {
+	header string
+	expiry time.Time
+}
+
+type tokenCache struct {
+	sync.Mutex
+	tokens map[string]cachedToken
+}
+
+func (c *tokenCache) get(key string) (string, bool) {
+	c.Lock()
+	defer c.Unlock()
+	token, ok := c.tokens[key]
+	if !ok || time.Now().After(token.expiry.Add(-tokenExpiryLeeway)) {
+		return "", false
+	}
+	return token.header, true
+}
+
+func (c *tokenCache) set(key string, token atc.AuthToken) string {
+	tokenType := token.Type
+	if tokenType == "" {
+		tokenType = defaultTokenType
+	}
+	header := tokenType + " " + token.Value
+
+	c.Lock()
+	defer c.Unlock()
+	c.tokens[key] = cachedToken{header: header, expiry: tokenExpiry(token.Value)}
+	return header
+}
+
+func (c *tokenCache) invalidate(key string) {
+	c.Lock()
+	defer c.Unlock()
+	delete(c.tokens, key)
+}
+
+// invalidateAuth removes the tokens of every team acquired from uri with auth
+func (c *tokenCache) invalidateAuth(uri string, auth *Auth) {
+	c.Lock()
+	defer c.Unlock()
+	prefix, suffix := uri+"/teams/", "#"+auth.hash()
+	for key := range c.tokens {
+		if strings.HasPrefix(key, prefix) && strings.HasSuffix(key, suffix) {
+			delete(c.tokens, key)
+		}
+	}
+}
+
+// tokenExpiry reads the exp claim of a JWT team token, falling back to the default TTL
+// for tokens that cannot be decoded
+func tokenExpiry(value string) time.Time {
+	fallback := time.Now().Add(defaultTokenTTL)
+	segments := strings.Split(value, ".")
+	if len(segments) != 3 {
+		return fallback
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(segments[1], "="))
+	if err != nil {
+		return fallback
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return fallback
+	}
+	return time.Unix(claims.Exp, 0)
+}
+
+// authTransport sets a fixed Authorization header on the requests it sends to one host. Requests
+// that follow a redirect to any other host are sent without it, so that the credentials are not
+// leaked to that host.
+type authTransport struct {
+	base   http.RoundTripper
+	host   string
+	header string
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host != t.host {
+		return t.base.RoundTrip(req)
+	}
+	authorised := req.Clone(req.Context())
+	authorised.Header.Set("Authorization", t.header)
+	return t.base.RoundTrip(authorised)
+}
+
+// withAuthorization returns a copy of httpClient that authorises its requests to the host of uri
+func withAuthorization(httpClient *http.Client, uri, header string) *http.Client {
+	host := ""
+	if parsed, err := url.Parse(uri); err == nil {
+		host = parsed.Host
+	}
+	authorised := *httpClient
+	authorised.Transport = &authTransport{base: httpClient.Transport, host: host, header: header}
+	return &authorised
+}
+
+// invalidateChangedAuth removes the cached tokens of the hosts of previous whose credentials
+// were rotated or removed in config
+func invalidateChangedAuth(previous, config *Config) {
+	configured := map[string]Host{}
+	for _, host := range config.allHosts() {
+		configured[host.Name()] = host
+	}
+	for _, old := range previous.allHosts() {
+		if old.Auth == nil {
+			continue
+		}
+		if host, ok := configured[old.Name()]; !ok || !reflect.DeepEqual(host.Auth, old.Auth) {
+			tokens.invalidateAuth(old.baseURL(previous), old.Auth)
+		}
+	}
+}
+
+// hash identifies the credentials in token cache keys without keeping them in the key
+func (a *Auth) hash() string {
+	sum := sha256.Sum256([]byte(a.Username + "\x00" + a.Password + "\x00" + a.Token))
+	return hex.EncodeToString(sum[:8])
+}
+
+// tokenKey identifies the token of a team acquired with auth, so that tokens are not shared
+// between different credentials for the same host
+func tokenKey(uri, team string, auth *Auth) string {
+	return uri + "/teams/" + team + "#" + auth.hash()
+}
+
+// authorisedClient returns a client for a host, exchanging the host credentials for a token
+// for team when the host has auth configured. How the token is acquired depends on the concourse
+// version of the host.
+func authorisedClient(uri string, host Host, team string, config *Config, metrics *Metrics) (*client, error) {
+	httpClient, err := createHTTPClient(config, host)
+	if err != nil {
+		return nil, err
+	}
+	if metrics != nil {
+		httpClient.Transport = &metricsTransport{base: httpClient.Transport, host: host.Name(), metrics: metrics}
+	}
+	c := &client{url: uri, httpClient: httpClient, generation: generation(versions.version(uri, httpClient))}
+	if host.Auth == nil {
+		return c, nil
+	}
+
+	key := tokenKey(uri, team, host.Auth)
+	header, ok := tokens.get(key)
+	if !ok {
+		header, err = c.token(team, host.Auth)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	c.httpClient = withAuthorization(httpClient, uri, header)
+	return c, nil
+}
+
+// token acquires a token for team and caches its Authorization header. Tokens from concourse
+// 4.0 onwards are not scoped to a team, and a configured token is used as it is.
+func (c *client) token(team string, auth *Auth) (string, error) {
+	var (
+		token atc.AuthToken
+		err   error
+	)
+	switch {
+	case c.generation == legacyAPI:
+		token, err = c.legacyToken(team, auth)
+	case auth.Token != "":
+		token = atc.AuthToken{Type: defaultTokenType, Value: auth.Token}
+	default:
+		token, err = c.skyToken(auth)
+	}
+	if err != nil {
+		return "", err
+	}
+	return tokens.set(tokenKey(c.url, team, auth), token), nil
+}
@@ -0,0 +1,216 @@
+package summary_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/gorilla/mux"
+
+	"github.com/FidelityInternational/go-concourse-summary/concourse"
+)
+
+var _ = Describe("Collector#Collect with auth", func() {
+	var (
+		config         *summary.Config
+		collector      *summary.Collector
+		snapshot       summary.Snapshot
+		authServer     *httptest.Server
+		tokenRequests  int
+		tokenAuthHeads []string
+		pipelineHeads  []string
+		auth           *summary.Auth
+		validToken     string
+		revoke         bool
+	)
+
+	BeforeEach(func() {
+		tokenRequests = 0
+		tokenAuthHeads = nil
+		pipelineHeads = nil
+		validToken = ""
+		revoke = false
+
+		router := mux.NewRouter()
+		router.HandleFunc("/api/v1/teams/main/auth/token", func(w http.ResponseWriter, r *http.Request) {
+			tokenRequests++
+			tokenAuthHeads = append(tokenAuthHeads, r.Header.Get("Authorization"))
+			validToken = fmt.Sprintf("team-token-%d", tokenRequests)
+			fmt.Fprintf(w, `{"type": "Bearer", "value": "%s"}`, validToken)
+		})
+		router.HandleFunc("/api/v1/teams/main/pipelines", func(w http.ResponseWriter, r *http.Request) {
+			pipelineHeads = append(pipelineHeads, r.Header.Get("Authorization"))
+			if r.Header.Get("Authorization") != "Bearer "+validToken {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, "[]")
+		})
+		authServer = httptest.NewServer(router)
+	})
+
+	AfterEach(func() {
+		authServer.Close()
+	})
+
+	JustBeforeEach(func() {
+		config = buildConfig(nil, "main", "http")
+		config.Hosts = []summary.Host{{FQDN: Host(authServer), Auth: auth}}
+		collector = summary.NewCollector(config)
+		snapshot = collector.Collect(config.Hosts[0])
+	})
+
+	Context("when the host has basic auth credentials", func() {
+		BeforeEach(func() {
+			auth = &summary.Auth{Username: "user", Password: "pass"}
+		})
+
+		It("exchanges the credentials for a team token", func() {
+			Ω(snapshot.Err).Should(BeNil())
+			Ω(tokenAuthHeads).Should(Equal([]string{"Basic dXNlcjpwYXNz"}))
+			Ω(pipelineHeads).Should(Equal([]string{"Bearer team-token-1"}))
+		})
+	})
+
+	Context("when the host has a bearer token", func() {
+		BeforeEach(func() {
+			auth = &summary.Auth{Token: "my-token"}
+		})
+
+		It("exchanges the bearer token for a team token", func() {
+			Ω(snapshot.Err).Should(BeNil())
+			Ω(tokenAuthHeads).Should(Equal([]string{"Bearer my-token"}))
+			Ω(pipelineHeads).Should(Equal([]string{"Bearer team-token-1"}))
+		})
+
+		Context("and the team token is already cached", func() {
+			JustBeforeEach(func() {
+				if revoke {
+					validToken = "revoked"
+				}
+				snapshot = collector.Collect(config.Hosts[0])
+			})
+
+			It("reuses the cached token", func() {
+				Ω(snapshot.Err).Should(BeNil())
+				Ω(tokenRequests).Should(Equal(1))
+				Ω(pipelineHeads).Should(Equal([]string{"Bearer team-token-1", "Bearer team-token-1"}))
+			})
+
+			Context("and the credentials have been rotated", func() {
+				JustBeforeEach(func() {
+					rotated := buildConfig(nil, "main", "http")
+					rotated.Hosts = []summary.Host{{FQDN: Host(authServer), Auth: &summary.Auth{Token: "rotated-token"}}}
+					collector.Reconfigure(rotated)
+					snapshot = collector.Collect(rotated.Hosts[0])
+				})
+
+				It("acquires a token with the new credentials", func() {
+					Ω(snapshot.Err).Should(BeNil())
+					Ω(tokenAuthHeads).Should(Equal([]string{"Bearer my-token", "Bearer rotated-token"}))
+				})
+			})
+
+			Context("and another alias reaches the host with different credentials", func() {
+				JustBeforeEach(func() {
+					snapshot = collector.Collect(summary.Host{FQDN: Host(authServer), Alias: "other", Auth: &summary.Auth{Token: "other-token"}})
+				})
+
+				It("does not share the token", func() {
+					Ω(snapshot.Err).Should(BeNil())
+					Ω(tokenAuthHeads).Should(Equal([]string{"Bearer my-token", "Bearer other-token"}))
+				})
+			})
+
+			Context("and the cached token has been revoked", func() {
+				BeforeEach(func() {
+					revoke = true
+				})
+
+				It("acquires a new token and retries", func() {
+					Ω(snapshot.Err).Should(BeNil())
+					Ω(tokenRequests).Should(Equal(2))
+					Ω(pipelineHeads).Should(Equal([]string{"Bearer team-token-1", "Bearer team-token-1", "Bearer team-token-2"}))
+				})
+			})
+		})
+	})
+
+	Context("when the host has no auth configured", func() {
+		BeforeEach(func() {
+			auth = nil
+		})
+
+		It("does not request a team token", func() {
+			Ω(snapshot.Err).Should(MatchError("not authorized"))
+			Ω(tokenRequests).Should(Equal(0))
+			Ω(pipelineHeads).Should(Equal([]string{""}))
+		})
+	})
+})
+
+var _ = Describe("Collector#Collect when a host redirects to another host", func() {
+	var (
+		version       string
+		auth          *summary.Auth
+		redirecting   *httptest.Server
+		other         *httptest.Server
+		otherHeads    []string
+		redirectedErr error
+	)
+
+	BeforeEach(func() {
+		otherHeads = nil
+		other = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			otherHeads = append(otherHeads, r.Header.Get("Authorization"))
+			w.WriteHeader(http.StatusUnauthorized)
+		}))
+	})
+
+	JustBeforeEach(func() {
+		router := mux.NewRouter()
+		router.HandleFunc("/api/v1/info", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `{"version": "%s", "worker_version": "2.2"}`, version)
+		})
+		router.PathPrefix("/").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, other.URL+r.URL.Path, http.StatusFound)
+		})
+		redirecting = httptest.NewServer(router)
+		collector := summary.NewCollector(buildConfig(nil, "main", "http"))
+		redirectedErr = collector.Collect(summary.Host{URL: redirecting.URL, Auth: auth}).Err
+	})
+
+	AfterEach(func() {
+		redirecting.Close()
+		other.Close()
+	})
+
+	Context("when exchanging credentials for a team token", func() {
+		BeforeEach(func() {
+			version = "3.14.1"
+			auth = &summary.Auth{Username: "user", Password: "pass"}
+		})
+
+		It("does not send the credentials to the other host", func() {
+			Ω(redirectedErr).ShouldNot(BeNil())
+			Ω(otherHeads).ShouldNot(BeEmpty())
+			Ω(otherHeads).ShouldNot(ContainElement(Not(BeEmpty())))
+		})
+	})
+
+	Context("when requesting pipelines with a bearer token", func() {
+		BeforeEach(func() {
+			version = "7.4.0"
+			auth = &summary.Auth{Token: "my-token"}
+		})
+
+		It("does not send the token to the other host", func() {
+			Ω(redirectedErr).ShouldNot(BeNil())
+			Ω(otherHeads).ShouldNot(BeEmpty())
+			Ω(otherHeads).ShouldNot(ContainElement(Not(BeEmpty())))
+		})
+	})
+})
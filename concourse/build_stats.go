@@ -0,0 +1,77 @@
+package summary
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// BuildStats are the durations of the latest builds of the jobs of a pipeline group
+type BuildStats struct {
+	// LatestDuration is the duration of the build that ended most recently
+	LatestDuration  time.Duration
+	AverageDuration time.Duration
+	P95Duration     time.Duration
+	// LatestEnd is when the build that ended most recently ended
+	LatestEnd time.Time
+	Builds    int
+}
+
+// newBuildStats returns the statistics of the latest builds of jobs that have start and end
+// times, or nil when none do
+func newBuildStats(jobs []Job) *BuildStats {
+	var (
+		stats     BuildStats
+		durations []time.Duration
+		total     time.Duration
+	)
+	for _, job := range jobs {
+		if job.StartTime.IsZero() || job.EndTime.IsZero() {
+			continue
+		}
+		duration := job.EndTime.Sub(job.StartTime)
+		durations = append(durations, duration)
+		total += duration
+		if job.EndTime.After(stats.LatestEnd) {
+			stats.LatestEnd = job.EndTime
+			stats.LatestDuration = duration
+		}
+	}
+	if len(durations) == 0 {
+		return nil
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	stats.Builds = len(durations)
+	stats.AverageDuration = total / time.Duration(len(durations))
+	stats.P95Duration = durations[int(math.Ceil(0.95*float64(len(durations))))-1]
+	return &stats
+}
+
+// Age returns how long ago the most recent build ended
+func (s BuildStats) Age() time.Duration {
+	return time.Since(s.LatestEnd)
+}
+
+// Summary describes the statistics for a tile, eg, "4m10s, avg 3m2s, p95 9m, 2h 5m ago"
+func (s BuildStats) Summary() string {
+	return fmt.Sprintf("%s, avg %s, p95 %s, %s ago",
+		s.LatestDuration.Round(time.Second), s.AverageDuration.Round(time.Second),
+		s.P95Duration.Round(time.Second), formatDuration(s.Age()))
+}
+
+// MarshalJSON represents durations and the age of the most recent build in seconds
+func (s BuildStats) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		LatestDuration  float64   `json:"latest_duration_seconds"`
+		AverageDuration float64   `json:"average_duration_seconds"`
+		P95Duration     float64   `json:"p95_duration_seconds"`
+		LatestEnd       time.Time `json:"latest_build_end"`
+		LatestAge       float64   `json:"latest_build_age_seconds"`
+		Builds          int       `json:"builds"`
+	}{
+		s.LatestDuration.Seconds(), s.AverageDuration.Seconds(), s.P95Duration.Seconds(),
+		s.LatestEnd, math.Round(s.Age().Seconds()), s.Builds,
+	})
+}
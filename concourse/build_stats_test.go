@@ -0,0 +1,115 @@
+package summary_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/gorilla/mux"
+
+	"github.com/FidelityInternational/go-concourse-summary/concourse"
+)
+
+var _ = Describe("Build stats", func() {
+	var (
+		concourse *fakeConcourse
+		config    *summary.Config
+		router    *mux.Router
+		host      summary.Host
+		jobs      string
+	)
+
+	hostPage := func() string {
+		mockRecorder := get(router, "/host/ci")
+		Ω(mockRecorder.Code).Should(Equal(200))
+		return mockRecorder.Body.String()
+	}
+
+	BeforeEach(func() {
+		now := time.Now().Unix()
+		// builds of 60s, 120s, 300s and 600s, the 120s build ending most recently, 2h ago
+		jobs = fmt.Sprintf(`[
+  {"id": 1, "name": "unit", "team_name": "main", "finished_build": {"id": 1, "name": "1", "status": "succeeded", "start_time": %d, "end_time": %d}},
+  {"id": 2, "name": "build", "team_name": "main", "finished_build": {"id": 2, "name": "1", "status": "succeeded", "start_time": %d, "end_time": %d}},
+  {"id": 3, "name": "deploy", "team_name": "main", "finished_build": {"id": 3, "name": "1", "status": "failed", "start_time": %d, "end_time": %d}},
+  {"id": 4, "name": "smoke", "team_name": "main", "finished_build": {"id": 4, "name": "1", "status": "succeeded", "start_time": %d, "end_time": %d}},
+  {"id": 5, "name": "release", "team_name": "main"}
+]`, now-10860, now-10800, now-7320, now-7200, now-14700, now-14400, now-18600, now-18000)
+		concourse = newFakeConcourse(pipelinesRoute(`[{"id": 1, "name": "pipeline-a", "team_name": "main"}]`))
+		host = concourse.host("ci")
+		config = pageConfig(host)
+	})
+
+	JustBeforeEach(func() {
+		concourse.mock(pipelineRoutes(jobs, "pipeline-a")...)
+		router = Router(config)
+	})
+
+	AfterEach(func() {
+		config.Collector.Stop()
+		concourse.Close()
+	})
+
+	It("adds the durations of the latest builds to the data", func() {
+		snapshot := config.Collector.Collect(host)
+		Ω(snapshot.Err).Should(BeNil())
+		Ω(snapshot.Data).Should(HaveLen(1))
+		stats := snapshot.Data[0].BuildStats
+		Ω(stats).ShouldNot(BeNil())
+		Ω(stats.Builds).Should(Equal(4))
+		Ω(stats.LatestDuration).Should(Equal(2 * time.Minute))
+		Ω(stats.AverageDuration).Should(Equal(4*time.Minute + 30*time.Second))
+		Ω(stats.P95Duration).Should(Equal(10 * time.Minute))
+		Ω(stats.Age()).Should(BeNumerically("~", 2*time.Hour, 5*time.Second))
+	})
+
+	It("includes the build stats in the JSON", func() {
+		snapshot := config.Collector.Collect(host)
+		body, err := json.Marshal(snapshot.Data[0])
+		Ω(err).Should(BeNil())
+		var value struct {
+			BuildStats map[string]interface{} `json:"build_stats"`
+		}
+		Ω(json.Unmarshal(body, &value)).Should(Succeed())
+		Ω(value.BuildStats).Should(HaveKeyWithValue("latest_duration_seconds", BeNumerically("==", 120)))
+		Ω(value.BuildStats).Should(HaveKeyWithValue("average_duration_seconds", BeNumerically("==", 270)))
+		Ω(value.BuildStats).Should(HaveKeyWithValue("p95_duration_seconds", BeNumerically("==", 600)))
+		Ω(value.BuildStats).Should(HaveKeyWithValue("latest_build_age_seconds", BeNumerically("~", 7200, 5)))
+		Ω(value.BuildStats).Should(HaveKeyWithValue("builds", BeNumerically("==", 4)))
+	})
+
+	Context("when no builds have times", func() {
+		BeforeEach(func() {
+			jobs = buildPayload("succeeded")
+		})
+
+		It("leaves the build stats out", func() {
+			snapshot := config.Collector.Collect(host)
+			Ω(snapshot.Data[0].BuildStats).Should(BeNil())
+			body, err := json.Marshal(snapshot.Data[0])
+			Ω(err).Should(BeNil())
+			Ω(string(body)).ShouldNot(ContainSubstring("build_stats"))
+		})
+	})
+
+	Context("when build stats are shown on tiles", func() {
+		BeforeEach(func() {
+			config.ShowBuildStats = true
+		})
+
+		It("shows them on the tile", func() {
+			config.Collector.Collect(host)
+			Ω(hostPage()).Should(ContainSubstring(`<span class="build_stats"><span>2m0s, avg 4m30s, p95 10m0s, 2h 0m ago</span></span>`))
+		})
+	})
+
+	Context("when build stats are not shown on tiles", func() {
+		It("leaves them off the tile", func() {
+			config.Collector.Collect(host)
+			Ω(hostPage()).ShouldNot(ContainSubstring("build_stats"))
+		})
+	})
+})
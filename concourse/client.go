@@ -0,0 +1,264 @@
+package summary
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/concourse/atc"
+	"github.com/concourse/go-concourse/concourse"
+)
+
+var (
+	versionTTL = 10 * time.Minute
+	// versionRetry is how long a version that could not be fetched is cached before it is tried again
+	versionRetry = 30 * time.Second
+	versions     = &versionCache{versions: map[string]cachedVersion{}}
+)
+
+// apiGeneration is the generation of the concourse API served by a host, which decides how
+// team tokens are acquired
+type apiGeneration int
+
+const (
+	// legacyAPI is served by concourse before 4.0, which issues team tokens from
+	// /api/v1/teams/{team}/auth/token
+	legacyAPI apiGeneration = iota
+	// skyAPI is served by concourse 4.0 onwards, which issues OAuth tokens from /sky/issuer/token,
+	// or from /sky/token before 7.0
+	skyAPI
+)
+
+// generation returns the API generation of a concourse version, treating versions that cannot
+// be parsed as legacy
+func generation(version string) apiGeneration {
+	major, err := strconv.Atoi(strings.SplitN(version, ".", 2)[0])
+	if err != nil || major < 4 {
+		return legacyAPI
+	}
+	return skyAPI
+}
+
+type cachedVersion struct {
+	version string
+	expiry  time.Time
+}
+
+type versionCache struct {
+	sync.Mutex
+	versions map[string]cachedVersion
+}
+
+// version returns the concourse version of the host at uri from GetInfo, caching it for a while
+// so that upgrades are noticed. When it cannot be fetched the last known version is kept, or a
+// blank version is returned when there is none, until it is tried again a little later.
+func (c *versionCache) version(uri string, httpClient *http.Client) string {
+	c.Lock()
+	cached, ok := c.versions[uri]
+	c.Unlock()
+	if ok && time.Now().Before(cached.expiry) {
+		return cached.version
+	}
+
+	info, err := (&client{url: uri, httpClient: httpClient}).getInfo()
+	c.Lock()
+	defer c.Unlock()
+	if err != nil {
+		fmt.Printf("Error fetching the version of concourse (%s): %s\n", uri, err.Error())
+		c.versions[uri] = cachedVersion{version: cached.version, expiry: time.Now().Add(versionRetry)}
+		return cached.version
+	}
+	c.versions[uri] = cachedVersion{version: info.Version, expiry: time.Now().Add(versionTTL)}
+	return info.Version
+}
+
+// client makes requests to the API of a concourse host. It understands the API of every
+// supported concourse version, from before 4.0 to 7.x.
+type client struct {
+	url        string
+	httpClient *http.Client
+	generation apiGeneration
+}
+
+// get fetches a path of the concourse API and decodes the JSON response into value
+func (c *client) get(path string, query url.Values, value interface{}) error {
+	resp, err := c.httpClient.Get(withQuery(c.url+path, query))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := checkResponse(resp); err != nil {
+		return err
+	}
+	return json.NewDecoder(resp.Body).Decode(value)
+}
+
+// checkResponse returns the same errors as the concourse client for unsuccessful responses
+func checkResponse(resp *http.Response) error {
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		return concourse.ErrUnauthorized
+	case resp.StatusCode == http.StatusForbidden:
+		return concourse.ErrForbidden
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("Unexpected Response\nStatus: %s\nBody:\n%s", resp.Status, body)
+	}
+	return nil
+}
+
+func (c *client) getInfo() (atc.Info, error) {
+	var info atc.Info
+	err := c.get("/api/v1/info", nil, &info)
+	return info, err
+}
+
+func (c *client) listTeams() ([]atc.Team, error) {
+	var teams []atc.Team
+	err := c.get("/api/v1/teams", nil, &teams)
+	return teams, err
+}
+
+// listPipelines fetches the pipelines of a team, including every instance of instanced pipelines
+// and leaving out archived pipelines
+func (c *client) listPipelines(teamName string) ([]pipelineInstance, error) {
+	var listed []pipelineInstance
+	if err := c.get(fmt.Sprintf("/api/v1/teams/%s/pipelines", url.PathEscape(teamName)), nil, &listed); err != nil {
+		return nil, err
+	}
+	var pipelines []pipelineInstance
+	for _, pipeline := range listed {
+		if !pipeline.Archived {
+			pipelines = append(pipelines, pipeline)
+		}
+	}
+	return pipelines, nil
+}
+
+// listWorkers fetches the workers of the host that the client's team can see
+func (c *client) listWorkers() ([]atc.Worker, error) {
+	var workers []atc.Worker
+	err := c.get("/api/v1/workers", nil, &workers)
+	return workers, err
+}
+
+// listJobs fetches the jobs of a pipeline instance
+func (c *client) listJobs(teamName string, p pipelineInstance) ([]atc.Job, error) {
+	var jobs []atc.Job
+	err := c.get(pipelinePath(teamName, p.Name)+"/jobs", p.InstanceVars.query(), &jobs)
+	return jobs, err
+}
+
+// listResources fetches the resources of a pipeline instance. From concourse 7.0 a resource
+// that is failing to check has a failed or errored check build rather than a check error.
+func (c *client) listResources(teamName string, p pipelineInstance) ([]atc.Resource, error) {
+	var listed []struct {
+		atc.Resource
+		CheckSetupError string `json:"check_setup_error"`
+		Build           *struct {
+			Status string `json:"status"`
+		} `json:"build"`
+	}
+	if err := c.get(pipelinePath(teamName, p.Name)+"/resources", p.InstanceVars.query(), &listed); err != nil {
+		return nil, err
+	}
+	resources := make([]atc.Resource, 0, len(listed))
+	for _, resource := range listed {
+		if resource.CheckError == "" && resource.CheckSetupError != "" {
+			resource.CheckError = resource.CheckSetupError
+		}
+		if resource.Build != nil && (resource.Build.Status == "failed" || resource.Build.Status == "errored") {
+			resource.FailingToCheck = true
+			if resource.CheckError == "" {
+				resource.CheckError = "check " + resource.Build.Status
+			}
+		}
+		resources = append(resources, resource.Resource)
+	}
+	return resources, nil
+}
+
+func pipelinePath(teamName, pipelineName string) string {
+	return fmt.Sprintf("/api/v1/teams/%s/pipelines/%s", url.PathEscape(teamName), url.PathEscape(pipelineName))
+}
+
+// legacyToken exchanges credentials for a team token using the concourse client, for hosts
+// before concourse 4.0
+func (c *client) legacyToken(team string, auth *Auth) (atc.AuthToken, error) {
+	credentialed := withAuthorization(c.httpClient, c.url, auth.header())
+	return concourse.NewClient(c.url, credentialed, false).Team(team).AuthToken()
+}
+
+// skyToken exchanges a username and password for a token using the OAuth password grant of the
+// sky endpoints, as fly does. Concourse 7.0 onwards issues the token from /sky/issuer/token, and
+// earlier versions from /sky/token.
+func (c *client) skyToken(auth *Auth) (atc.AuthToken, error) {
+	token, err := c.passwordGrant("/sky/issuer/token", auth)
+	if err == errTokenEndpointNotFound {
+		token, err = c.passwordGrant("/sky/token", auth)
+	}
+	return token, err
+}
+
+var errTokenEndpointNotFound = fmt.Errorf("token endpoint not found")
+
+func (c *client) passwordGrant(path string, auth *Auth) (atc.AuthToken, error) {
+	form := url.Values{
+		"grant_type": {"password"},
+		"username":   {auth.Username},
+		"password":   {auth.Password},
+		"scope":      {"openid profile email federated:id groups"},
+	}
+	req, err := http.NewRequest("POST", c.url+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return atc.AuthToken{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth("fly", "Zmx5")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return atc.AuthToken{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return atc.AuthToken{}, errTokenEndpointNotFound
+	}
+	if resp.StatusCode == http.StatusBadRequest {
+		// the password grant responds with invalid_grant when the credentials are wrong
+		return atc.AuthToken{}, concourse.ErrUnauthorized
+	}
+	if err := checkResponse(resp); err != nil {
+		return atc.AuthToken{}, err
+	}
+
+	var token struct {
+		TokenType   string `json:"token_type"`
+		AccessToken string `json:"access_token"`
+		IDToken     string `json:"id_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return atc.AuthToken{}, err
+	}
+	// concourse 7.0 onwards authenticates API requests with the id token from the issuer endpoint,
+	// while earlier releases only accept the access token from /sky/token
+	value := token.AccessToken
+	if path == "/sky/issuer/token" && token.IDToken != "" {
+		value = token.IDToken
+	}
+	return atc.AuthToken{Type: bearerType(token.TokenType), Value: value}, nil
+}
+
+// bearerType normalises the lower case token type returned by OAuth servers
+func bearerType(tokenType string) string {
+	if strings.EqualFold(tokenType, "bearer") || tokenType == "" {
+		return defaultTokenType
+	}
+	return tokenType
+}
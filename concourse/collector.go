@@ -0,0 +1,363 @@
+package summary
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+var adHocHostTTL = 10 * time.Minute
+
+// Snapshot is the result of the most recent collection from a concourse host
+type Snapshot struct {
+	Data []Data
+	Err  error
+	// Workers is nil when the workers could not be collected
+	Workers   *WorkerSummary
+	UpdatedAt time.Time
+}
+
+// Store holds the latest snapshot for each host and is safe for concurrent use
+type Store struct {
+	sync.RWMutex
+	snapshots map[string]Snapshot
+}
+
+// NewStore - creates an empty snapshot store
+func NewStore() *Store {
+	return &Store{snapshots: map[string]Snapshot{}}
+}
+
+// Get returns the latest snapshot for a host and whether one has been collected yet
+func (s *Store) Get(host string) (Snapshot, bool) {
+	s.RLock()
+	defer s.RUnlock()
+	snapshot, ok := s.snapshots[host]
+	return snapshot, ok
+}
+
+// All returns a copy of the latest snapshot of every host
+func (s *Store) All() map[string]Snapshot {
+	s.RLock()
+	defer s.RUnlock()
+	snapshots := make(map[string]Snapshot, len(s.snapshots))
+	for host, snapshot := range s.snapshots {
+		snapshots[host] = snapshot
+	}
+	return snapshots
+}
+
+// Set replaces the snapshot held for a host
+func (s *Store) Set(host string, snapshot Snapshot) {
+	s.Lock()
+	defer s.Unlock()
+	s.snapshots[host] = snapshot
+}
+
+// Delete removes the snapshot held for a host
+func (s *Store) Delete(host string) {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.snapshots, host)
+}
+
+// Collector polls concourse hosts in the background and keeps a store of their latest data
+type Collector struct {
+	Store   *Store
+	Metrics *Metrics
+
+	mu      sync.Mutex
+	config  *Config
+	started bool
+	pollers map[string]*poller
+	// generations counts the times polling of each host was stopped, so that a collection that
+	// was in flight when its host was stopped is not stored
+	generations map[string]int
+
+	subscribersMu sync.Mutex
+	subscribers   map[chan struct{}]bool
+
+	notifier *notifier
+	hist     *history
+	states   *stateTracker
+}
+
+type poller struct {
+	host     Host
+	adHoc    bool
+	interval time.Duration
+	lastSeen time.Time
+	stop     chan struct{}
+}
+
+// compileUncompiled compiles the pipelines of a config that was not set up by SetupConfig or
+// SetupConfigFromFile
+func compileUncompiled(config *Config) {
+	if config.patterns != nil {
+		return
+	}
+	if err := config.compilePipelines(); err != nil {
+		fmt.Printf("Error compiling pipelines, pipelines that do not compile match nothing: %s\n", err.Error())
+	}
+}
+
+// NewCollector - creates a collector for the hosts in config
+func NewCollector(config *Config) *Collector {
+	compileUncompiled(config)
+	hist, err := newHistory(config)
+	if err != nil {
+		fmt.Printf("Error opening history, history is disabled: %s\n", err.Error())
+	}
+	return &Collector{
+		config:  config,
+		Store:   NewStore(),
+		Metrics: NewMetrics(),
+		pollers: map[string]*poller{},
+
+		generations: map[string]int{},
+		subscribers: map[chan struct{}]bool{},
+		notifier:    newNotifier(),
+		hist:        hist,
+		states:      newStateTracker(),
+	}
+}
+
+// Start begins polling every host in the config, including those only defined in groups
+func (c *Collector) Start() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.started = true
+	for _, host := range c.config.allHosts() {
+		c.start(host, false)
+	}
+}
+
+// Stop stops polling all hosts
+func (c *Collector) Stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.started = false
+	for fqdn := range c.pollers {
+		c.stop(fqdn)
+	}
+}
+
+// Reconfigure switches the collector to a new config. Hosts that are no longer configured stop
+// being polled, new hosts start being polled and hosts whose settings or refresh interval changed
+// are restarted.
+func (c *Collector) Reconfigure(config *Config) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !reflect.DeepEqual(config.History, c.config.History) || config.HistoryStore != c.config.HistoryStore {
+		hist, err := newHistory(config)
+		if err != nil {
+			fmt.Printf("Error opening history, keeping the current history: %s\n", err.Error())
+		} else {
+			c.hist = hist
+		}
+	}
+	compileUncompiled(config)
+	invalidateChangedAuth(c.config, config)
+	c.config = config
+
+	configured := map[string]Host{}
+	for _, host := range config.allHosts() {
+		configured[host.Name()] = host
+	}
+	for fqdn, p := range c.pollers {
+		host, ok := configured[fqdn]
+		if !ok && !p.adHoc {
+			c.stop(fqdn)
+			c.forget(fqdn)
+		}
+		if ok && (p.adHoc || !reflect.DeepEqual(host, p.host) || c.interval(host) != p.interval) {
+			c.stop(fqdn)
+		}
+		if !ok && p.adHoc && c.interval(p.host) != p.interval {
+			c.stop(fqdn)
+			c.start(p.host, true)
+			c.pollers[fqdn].lastSeen = p.lastSeen
+		}
+	}
+	if !c.started {
+		return
+	}
+	for fqdn, host := range configured {
+		if _, ok := c.pollers[fqdn]; !ok {
+			c.start(host, false)
+		}
+	}
+}
+
+func (c *Collector) history() *history {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hist
+}
+
+func (c *Collector) currentConfig() *Config {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.config
+}
+
+// Watch begins polling a host that is not in the config. Polling stops once the host has
+// not been watched for a while.
+func (c *Collector) Watch(host Host) {
+	c.poll(host, true)
+}
+
+// Collect fetches data from a host and stores it, returning the new snapshot. The snapshot is
+// not stored when polling of the host was stopped while it was being collected.
+func (c *Collector) Collect(host Host) Snapshot {
+	start := time.Now()
+	c.mu.Lock()
+	config, generation := c.config, c.generations[host.Name()]
+	c.mu.Unlock()
+	data, err := getData(host, config, c.Metrics)
+	var workers *WorkerSummary
+	// listing workers needs credentials, so they are not collected from public hosts
+	if err == nil && host.Auth != nil {
+		var workersErr error
+		if workers, workersErr = getWorkers(host, config, c.Metrics); workersErr != nil {
+			fmt.Printf("Error collecting workers from concourse (%s): %s\n", host.Name(), workersErr.Error())
+		}
+	}
+	c.Metrics.collected(host.Name(), time.Since(start), err)
+	snapshot := Snapshot{Data: data, Err: err, Workers: workers, UpdatedAt: time.Now()}
+	if err != nil {
+		fmt.Printf("Error collecting data from concourse (%s): %s\n", host.Name(), err.Error())
+	}
+	if !c.store(host, generation, snapshot, config) {
+		return snapshot
+	}
+	c.notify()
+	return snapshot
+}
+
+// store records a snapshot unless polling of its host has been stopped since generation
+func (c *Collector) store(host Host, generation int, snapshot Snapshot, config *Config) bool {
+	c.mu.Lock()
+	if c.generations[host.Name()] != generation {
+		if _, ok := c.pollers[host.Name()]; !ok {
+			// the collection may have recorded metrics after the host was forgotten
+			c.Metrics.forget(host.Name())
+		}
+		c.mu.Unlock()
+		return false
+	}
+	c.states.track(host.Name(), snapshot)
+	c.Store.Set(host.Name(), snapshot)
+	hist := c.hist
+	c.mu.Unlock()
+
+	// recording may write to a file, so it is done without holding up other collections
+	if hist != nil {
+		hist.record(host.Name(), snapshot)
+	}
+	c.notifier.collected(host, snapshot, config)
+	return true
+}
+
+// Subscribe returns a channel that is signalled after each collection and a function that
+// unsubscribes it. Signals are coalesced while the subscriber is busy.
+func (c *Collector) Subscribe() (<-chan struct{}, func()) {
+	updates := make(chan struct{}, 1)
+	c.subscribersMu.Lock()
+	defer c.subscribersMu.Unlock()
+	c.subscribers[updates] = true
+	return updates, func() {
+		c.subscribersMu.Lock()
+		defer c.subscribersMu.Unlock()
+		delete(c.subscribers, updates)
+	}
+}
+
+func (c *Collector) notify() {
+	c.subscribersMu.Lock()
+	defer c.subscribersMu.Unlock()
+	for updates := range c.subscribers {
+		select {
+		case updates <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (c *Collector) poll(host Host, adHoc bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if p, ok := c.pollers[host.Name()]; ok {
+		p.lastSeen = time.Now()
+		return
+	}
+	c.start(host, adHoc)
+}
+
+// start begins polling a host, c.mu must be held
+func (c *Collector) start(host Host, adHoc bool) {
+	p := &poller{host: host, adHoc: adHoc, interval: c.interval(host), lastSeen: time.Now(), stop: make(chan struct{})}
+	c.pollers[host.Name()] = p
+	go c.run(p, p.interval)
+}
+
+// stop stops polling a host, c.mu must be held
+func (c *Collector) stop(fqdn string) {
+	close(c.pollers[fqdn].stop)
+	delete(c.pollers, fqdn)
+	c.generations[fqdn]++
+}
+
+func (c *Collector) run(p *poller, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		c.Collect(p.host)
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		}
+		if c.expired(p) {
+			return
+		}
+	}
+}
+
+// expired removes an ad hoc poller that nobody has watched recently, along with everything kept
+// about its host
+func (c *Collector) expired(p *poller) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !p.adHoc || time.Since(p.lastSeen) < adHocHostTTL {
+		return false
+	}
+	if c.pollers[p.host.Name()] == p {
+		c.stop(p.host.Name())
+		c.forget(p.host.Name())
+	}
+	return true
+}
+
+// forget drops the snapshot, metrics and notification and state tracking data of a host that
+// is no longer polled, c.mu must be held
+func (c *Collector) forget(fqdn string) {
+	c.Store.Delete(fqdn)
+	c.Metrics.forget(fqdn)
+	c.notifier.forget(fqdn)
+	c.states.forget(fqdn)
+	transports.forget(fqdn)
+}
+
+// interval returns how often a host is polled, c.mu must be held
+func (c *Collector) interval(host Host) time.Duration {
+	interval := host.Interval
+	if interval < 1 {
+		interval = c.config.RefreshInterval
+	}
+	if interval < 1 {
+		interval = defaultRefreshInterval
+	}
+	return time.Duration(interval) * time.Second
+}
@@ -0,0 +1,252 @@
+package summary_test
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/FidelityInternational/go-concourse-summary/concourse"
+)
+
+var _ = Describe("Collector", func() {
+	var (
+		config    *summary.Config
+		collector *summary.Collector
+	)
+
+	BeforeEach(func() {
+		mocks := []MockRoute{
+			{"GET", "/api/v1/teams/main/pipelines", pipelinesPayload, 200, "", nil},
+			{"GET", "/api/v1/teams/main/pipelines/test1/jobs", jobsPayload, 200, "", nil},
+			{"GET", "/api/v1/teams/main/pipelines/test1/resources", "[]", 200, "", nil},
+		}
+		setupMultiple(mocks)
+		config = buildConfig(nil, "main", "http")
+	})
+
+	AfterEach(func() {
+		collector.Stop()
+		teardown()
+	})
+
+	Describe("#Collect", func() {
+		It("stores a snapshot of the host data", func() {
+			collector = summary.NewCollector(config)
+			snapshot := collector.Collect(summary.Host{FQDN: Host(server)})
+			Ω(snapshot.Err).Should(BeNil())
+			Ω(snapshot.UpdatedAt).ShouldNot(BeZero())
+			Ω(snapshot.Data).Should(HaveLen(1))
+
+			stored, ok := collector.Store.Get(Host(server))
+			Ω(ok).Should(BeTrue())
+			Ω(stored).Should(Equal(snapshot))
+		})
+
+		It("reuses connections between collections", func() {
+			var newConns int32
+			reused := httptest.NewUnstartedServer(server.Config.Handler)
+			reused.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+				if state == http.StateNew {
+					atomic.AddInt32(&newConns, 1)
+				}
+			}
+			reused.Start()
+			defer reused.Close()
+
+			collector = summary.NewCollector(config)
+			host := summary.Host{URL: reused.URL, Concurrency: 1}
+			for i := 0; i < 20; i++ {
+				Ω(collector.Collect(host).Err).Should(BeNil())
+			}
+			Ω(atomic.LoadInt32(&newConns)).Should(Equal(int32(1)))
+		})
+
+		Context("when a pipeline has a resource failing to check", func() {
+			BeforeEach(func() {
+				teardown()
+				mocks := []MockRoute{
+					{"GET", "/api/v1/teams/main/pipelines", examplePipeline, 200, "", nil},
+					{"GET", "/api/v1/teams/main/pipelines/cf-example-pipeline/jobs", examplePipelineJobs, 200, "", nil},
+					{"GET", "/api/v1/teams/main/pipelines/cf-example-pipeline/resources", examplePipelineBrokenResources, 200, "", nil},
+				}
+				setupMultiple(mocks)
+				collector = summary.NewCollector(config)
+			})
+
+			It("marks the groups of the pipeline as broken, even by resources no job uses", func() {
+				snapshot := collector.Collect(summary.Host{FQDN: Host(server)})
+				Ω(snapshot.Err).Should(BeNil())
+				Ω(snapshot.Data).Should(HaveLen(1))
+				Ω(snapshot.Data[0].BrokenResource).Should(BeTrue())
+				Ω(snapshot.Data[0].BrokenResources).Should(Equal([]summary.BrokenResource{
+					{Name: "testResource1", CheckError: "git clone failed: authentication required"},
+					{Name: "unusedResource"},
+				}))
+				Ω(snapshot.Data[0].BrokenResourceSummary()).Should(Equal("testResource1: git clone failed: authentication required\nunusedResource: failing to check"))
+			})
+		})
+
+		Context("when the host has many pipelines", func() {
+			var jobsStatus int
+
+			BeforeEach(func() {
+				jobsStatus = 200
+			})
+
+			JustBeforeEach(func() {
+				teardown()
+				mocks := []MockRoute{
+					{"GET", "/api/v1/teams/main/pipelines", multiplePipelinesPayload, 200, "", nil},
+					{"GET", "/api/v1/teams/main/pipelines/pipeline-a/jobs", jobsPayload, 200, "", nil},
+					{"GET", "/api/v1/teams/main/pipelines/pipeline-a/resources", "[]", 200, "", nil},
+					{"GET", "/api/v1/teams/main/pipelines/pipeline-b/jobs", jobsPayload, jobsStatus, "", nil},
+					{"GET", "/api/v1/teams/main/pipelines/pipeline-b/resources", "[]", 200, "", nil},
+					{"GET", "/api/v1/teams/main/pipelines/pipeline-c/jobs", examplePipelineJobs, 200, "", nil},
+					{"GET", "/api/v1/teams/main/pipelines/pipeline-c/resources", "[]", 200, "", nil},
+				}
+				setupMultiple(mocks)
+				collector = summary.NewCollector(config)
+			})
+
+			It("collects the jobs of every pipeline in a deterministic order", func() {
+				snapshot := collector.Collect(summary.Host{FQDN: Host(server), Concurrency: 2})
+				Ω(snapshot.Err).Should(BeNil())
+				Ω(snapshot.Data).Should(HaveLen(3))
+				Ω(snapshot.Data[0].Pipeline).Should(Equal("pipeline-a"))
+				Ω(snapshot.Data[0].Paused).Should(BeTrue())
+				Ω(snapshot.Data[1].Pipeline).Should(Equal("pipeline-b"))
+				Ω(snapshot.Data[2].Pipeline).Should(Equal("pipeline-c"))
+				Ω(snapshot.Data[2].Group).Should(Equal("test-group"))
+				Ω(snapshot.Data[2].Statuses).Should(Equal(map[string]int{"succeeded": 2}))
+			})
+
+			It("fetches the jobs of several pipelines at a time, up to the concurrency of the host", func() {
+				var inFlight, peak int32
+				handler := server.Config.Handler
+				counting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if strings.HasSuffix(r.URL.Path, "/jobs") {
+						current := atomic.AddInt32(&inFlight, 1)
+						defer atomic.AddInt32(&inFlight, -1)
+						for {
+							highest := atomic.LoadInt32(&peak)
+							if current <= highest || atomic.CompareAndSwapInt32(&peak, highest, current) {
+								break
+							}
+						}
+						time.Sleep(50 * time.Millisecond)
+					}
+					handler.ServeHTTP(w, r)
+				}))
+				defer counting.Close()
+
+				snapshot := collector.Collect(summary.Host{URL: counting.URL, Concurrency: 2})
+				Ω(snapshot.Err).Should(BeNil())
+				Ω(atomic.LoadInt32(&peak)).Should(BeNumerically(">", 1))
+				Ω(atomic.LoadInt32(&peak)).Should(BeNumerically("<=", 2))
+			})
+
+			Context("and the jobs of one pipeline cannot be fetched", func() {
+				BeforeEach(func() {
+					jobsStatus = 500
+				})
+
+				It("returns an error", func() {
+					snapshot := collector.Collect(summary.Host{FQDN: Host(server), Concurrency: 2})
+					Ω(snapshot.Err).Should(HaveOccurred())
+					Ω(snapshot.Data).Should(BeEmpty())
+				})
+			})
+		})
+	})
+
+	Describe("#Start", func() {
+		It("polls hosts defined in hosts and groups", func() {
+			config.CSGroups = summary.CSGroups{
+				{
+					Group: "test",
+					Hosts: []summary.Host{{FQDN: Host(server)}},
+				},
+			}
+			collector = summary.NewCollector(config)
+			collector.Start()
+
+			Eventually(func() bool {
+				_, ok := collector.Store.Get(Host(server))
+				return ok
+			}).Should(BeTrue())
+		})
+	})
+
+	Describe("#Watch", func() {
+		It("polls a host that is not configured", func() {
+			collector = summary.NewCollector(config)
+			_, ok := collector.Store.Get(Host(server))
+			Ω(ok).Should(BeFalse())
+
+			collector.Watch(summary.Host{FQDN: Host(server)})
+
+			Eventually(func() []summary.Data {
+				snapshot, _ := collector.Store.Get(Host(server))
+				return snapshot.Data
+			}).Should(HaveLen(1))
+		})
+	})
+
+	Describe("#Reconfigure", func() {
+		It("forgets the hosts that are no longer configured", func() {
+			config.Hosts = []summary.Host{{FQDN: Host(server)}}
+			config.RefreshInterval = 3600
+			collector = summary.NewCollector(config)
+			collector.Start()
+			Eventually(func() bool {
+				_, ok := collector.Store.Get(Host(server))
+				return ok
+			}).Should(BeTrue())
+
+			reconfigured := buildConfig(nil, "main", "http")
+			collector.Reconfigure(reconfigured)
+			_, ok := collector.Store.Get(Host(server))
+			Ω(ok).Should(BeFalse())
+			reconfigured.Collector = collector
+			mockRecorder := httptest.NewRecorder()
+			reconfigured.Metrics(mockRecorder, httptest.NewRequest("GET", "/metrics", nil))
+			Ω(mockRecorder.Body.String()).ShouldNot(ContainSubstring(Host(server)))
+		})
+
+		It("does not store a collection that was in flight when its host was removed", func() {
+			requested, release := make(chan bool, 1), make(chan bool)
+			blocking := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/api/v1/info" {
+					fmt.Fprint(w, legacyInfoPayload)
+					return
+				}
+				requested <- true
+				<-release
+				fmt.Fprint(w, "[]")
+			}))
+			defer blocking.Close()
+
+			host := summary.Host{URL: blocking.URL, Alias: "removed"}
+			config.Hosts = []summary.Host{host}
+			config.RefreshInterval = 3600
+			collector = summary.NewCollector(config)
+			collector.Start()
+			Eventually(requested).Should(Receive())
+
+			collector.Reconfigure(buildConfig(nil, "main", "http"))
+			close(release)
+
+			Consistently(func() bool {
+				_, ok := collector.Store.Get("removed")
+				return ok
+			}, 200*time.Millisecond).Should(BeFalse())
+		})
+	})
+})
@@ -79,3 +79,27 @@ const pipelinesPayload = `[
     "team_name": "main"
   }
 ]`
+
+const multiplePipelinesPayload = `[
+  {
+    "id": 1,
+    "name": "pipeline-b",
+    "paused": false,
+    "public": true,
+    "team_name": "main"
+  },
+  {
+    "id": 2,
+    "name": "pipeline-a",
+    "paused": true,
+    "public": true,
+    "team_name": "main"
+  },
+  {
+    "id": 3,
+    "name": "pipeline-c",
+    "paused": false,
+    "public": true,
+    "team_name": "main"
+  }
+]`
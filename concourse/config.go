@@ -0,0 +1,419 @@
+package summary
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// fileConfig is the structure of a YAML or JSON configuration file
+type fileConfig struct {
+	RefreshInterval   *int           `json:"refresh_interval"`
+	SkipSSLValidation *bool          `json:"skip_ssl_validation"`
+	Team              string         `json:"team"`
+	AllowAdHocHosts   *bool          `json:"allow_ad_hoc_hosts"`
+	Hosts             []Host         `json:"hosts"`
+	Groups            CSGroups       `json:"groups"`
+	Notifications     []Notification `json:"notifications"`
+	History           *HistoryConfig `json:"history"`
+	ShowBuildStats    *bool          `json:"show_build_stats"`
+	StaleAfter        string         `json:"stale_after"`
+}
+
+// ConfigError is a validation error for a single key of the configuration
+type ConfigError struct {
+	Key     string
+	Message string
+}
+
+func (e ConfigError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Key, e.Message)
+}
+
+// SetupConfigFromFile sets up a config object from a YAML or JSON config file. Environment values
+// that are not blank override the values in the file.
+func SetupConfigFromFile(path, refreshInterval, groupsJSON, hostsJSON, skipSSLValidationString, teamName string) (*Config, error) {
+	file, err := readConfigFile(path)
+	if err != nil {
+		return &Config{}, err
+	}
+
+	config, err := SetupConfig(refreshInterval, groupsJSON, hostsJSON, skipSSLValidationString, teamName)
+	if err != nil {
+		return &Config{}, err
+	}
+
+	if refreshInterval == "" && file.RefreshInterval != nil && *file.RefreshInterval >= 1 {
+		config.RefreshInterval = *file.RefreshInterval
+	}
+	if groupsJSON == "" && file.Groups != nil {
+		config.CSGroups = file.Groups
+	}
+	if hostsJSON == "" && file.Hosts != nil {
+		config.Hosts = file.Hosts
+	}
+	if skipSSLValidationString == "" && file.SkipSSLValidation != nil {
+		config.SkipSSLValidation = *file.SkipSSLValidation
+	}
+	if teamName == "" && file.Team != "" {
+		config.Team = file.Team
+	}
+	if file.AllowAdHocHosts != nil {
+		config.AllowAdHocHosts = *file.AllowAdHocHosts
+	}
+	config.Notifications = file.Notifications
+	config.History = file.History
+	if file.ShowBuildStats != nil {
+		config.ShowBuildStats = *file.ShowBuildStats
+	}
+	config.StaleAfter = file.StaleAfter
+
+	if err := validateAliases(config, "hosts", "groups"); err != nil {
+		return &Config{}, fmt.Errorf("%s: %s", path, err.Error())
+	}
+	if err := config.compilePipelines(); err != nil {
+		return &Config{}, fmt.Errorf("%s: %s", path, err.Error())
+	}
+	return config, nil
+}
+
+// compilePipelines compiles the patterns of the pipelines of every host once, rather than each
+// time data is matched
+func (config *Config) compilePipelines() error {
+	compiled := patterns{}
+	if err := compiled.add(config.allPipelines()); err != nil {
+		return err
+	}
+	config.patterns = compiled
+	return nil
+}
+
+func (config *Config) allPipelines() []Pipeline {
+	var pipelines []Pipeline
+	for _, host := range config.Hosts {
+		pipelines = append(pipelines, host.Pipelines...)
+	}
+	for _, csGroup := range config.CSGroups {
+		for _, host := range csGroup.Hosts {
+			pipelines = append(pipelines, host.Pipelines...)
+		}
+	}
+	return pipelines
+}
+
+// readConfigFile parses and validates a config file. YAML is a superset of JSON so both are
+// read as YAML, then converted to JSON so that the same field names and parsing apply as for
+// the environment variables.
+func readConfigFile(path string) (fileConfig, error) {
+	var file fileConfig
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return file, err
+	}
+
+	var raw interface{}
+	if err := yaml.Unmarshal(contents, &raw); err != nil {
+		return file, fmt.Errorf("%s: %s", path, err.Error())
+	}
+	raw, err = jsonCompatible(raw, "")
+	if err != nil {
+		return file, fmt.Errorf("%s: %s", path, err.Error())
+	}
+	if raw == nil {
+		return file, nil
+	}
+	if err := checkKeys(raw, reflect.TypeOf(file), ""); err != nil {
+		return file, fmt.Errorf("%s: %s", path, err.Error())
+	}
+
+	converted, err := json.Marshal(raw)
+	if err != nil {
+		return file, fmt.Errorf("%s: %s", path, err.Error())
+	}
+	if err := json.Unmarshal(converted, &file); err != nil {
+		if typeErr, ok := err.(*json.UnmarshalTypeError); ok {
+			return file, fmt.Errorf("%s: %s", path, ConfigError{Key: typeErr.Field, Message: fmt.Sprintf("cannot use %s as %s", typeErr.Value, typeErr.Type)})
+		}
+		return file, fmt.Errorf("%s: %s", path, err.Error())
+	}
+
+	if err := validateHosts("hosts", file.Hosts); err != nil {
+		return file, fmt.Errorf("%s: %s", path, err.Error())
+	}
+	if err := validateGroups("groups", file.Groups); err != nil {
+		return file, fmt.Errorf("%s: %s", path, err.Error())
+	}
+	if err := validateNotifications("notifications", file.Notifications); err != nil {
+		return file, fmt.Errorf("%s: %s", path, err.Error())
+	}
+	if err := validateDuration("stale_after", file.StaleAfter); err != nil {
+		return file, fmt.Errorf("%s: %s", path, err.Error())
+	}
+	if file.History != nil {
+		if err := file.History.validate("history"); err != nil {
+			return file, fmt.Errorf("%s: %s", path, err.Error())
+		}
+	}
+	return file, nil
+}
+
+// jsonCompatible converts the maps produced by the YAML parser, which may have keys of any type,
+// to maps with string keys
+func jsonCompatible(value interface{}, key string) (interface{}, error) {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		converted := map[string]interface{}{}
+		for k, item := range v {
+			name, ok := k.(string)
+			if !ok {
+				return nil, ConfigError{Key: key, Message: fmt.Sprintf("key %v must be a string", k)}
+			}
+			convertedItem, err := jsonCompatible(item, joinKey(key, name))
+			if err != nil {
+				return nil, err
+			}
+			converted[name] = convertedItem
+		}
+		return converted, nil
+	case []interface{}:
+		for i, item := range v {
+			convertedItem, err := jsonCompatible(item, fmt.Sprintf("%s[%d]", key, i))
+			if err != nil {
+				return nil, err
+			}
+			v[i] = convertedItem
+		}
+		return v, nil
+	default:
+		return value, nil
+	}
+}
+
+// checkKeys reports the first key in value that has no matching json field in t
+func checkKeys(value interface{}, t reflect.Type, key string) error {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if t.Kind() != reflect.Struct {
+			return nil
+		}
+		fields := jsonFields(t)
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			field, ok := fields[name]
+			if !ok {
+				return ConfigError{Key: joinKey(key, name), Message: "unknown key"}
+			}
+			if err := checkKeys(v[name], field, joinKey(key, name)); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		if t.Kind() != reflect.Slice {
+			return nil
+		}
+		for i, item := range v {
+			if err := checkKeys(item, t.Elem(), fmt.Sprintf("%s[%d]", key, i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		fields[name] = field.Type
+	}
+	return fields
+}
+
+func joinKey(key, name string) string {
+	if key == "" {
+		return name
+	}
+	return key + "." + name
+}
+
+// validateAliases checks that every host with the same alias is reached at the same URL, as the
+// alias is all that identifies a host in page URLs, and that its entries do not set different
+// values for a setting. A setting left unset by an entry is inherited from the others, as the
+// host is collected once for all of them.
+func validateAliases(config *Config, hostsKey, groupsKey string) error {
+	type setting struct {
+		key   string
+		value interface{}
+	}
+	urls := map[string]string{}
+	settings := map[string]map[string]setting{}
+	check := func(key string, host Host) error {
+		baseURL := host.baseURL(config)
+		if existing, ok := urls[host.Name()]; ok && existing != baseURL {
+			return ConfigError{Key: key, Message: fmt.Sprintf("alias %s is already used for %s", host.Name(), existing)}
+		}
+		urls[host.Name()] = baseURL
+		if settings[host.Name()] == nil {
+			settings[host.Name()] = map[string]setting{}
+		}
+		for _, s := range []struct {
+			name  string
+			value interface{}
+			set   bool
+		}{
+			{"auth", host.Auth, host.Auth != nil},
+			{"tls", host.TLS, host.TLS != nil},
+			{"interval", host.Interval, host.Interval != 0},
+			{"concurrency", host.Concurrency, host.Concurrency != 0},
+		} {
+			if !s.set {
+				continue
+			}
+			existing, ok := settings[host.Name()][s.name]
+			if !ok {
+				settings[host.Name()][s.name] = setting{key: key, value: s.value}
+				continue
+			}
+			if !reflect.DeepEqual(existing.value, s.value) {
+				return ConfigError{Key: key, Message: fmt.Sprintf("alias %s is already used by %s with a different %s", host.Name(), existing.key, s.name)}
+			}
+		}
+		return nil
+	}
+	for i, host := range config.Hosts {
+		if err := check(fmt.Sprintf("%s[%d]", hostsKey, i), host); err != nil {
+			return err
+		}
+	}
+	for i, csGroup := range config.CSGroups {
+		for j, host := range csGroup.Hosts {
+			if err := check(fmt.Sprintf("%s[%d].hosts[%d]", groupsKey, i, j), host); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateHosts(key string, hosts []Host) error {
+	for i, host := range hosts {
+		if err := host.validate(fmt.Sprintf("%s[%d]", key, i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateGroups(key string, groups CSGroups) error {
+	seen := map[string]bool{}
+	for i, group := range groups {
+		groupKey := fmt.Sprintf("%s[%d]", key, i)
+		if group.Group == "" {
+			return ConfigError{Key: groupKey + ".group", Message: "must not be blank"}
+		}
+		if seen[group.Group] {
+			return ConfigError{Key: groupKey + ".group", Message: fmt.Sprintf("group %s is defined more than once", group.Group)}
+		}
+		seen[group.Group] = true
+		if err := validateHosts(groupKey+".hosts", group.Hosts); err != nil {
+			return err
+		}
+		if err := validateNotifications(groupKey+".notifications", group.Notifications); err != nil {
+			return err
+		}
+		if err := validateDuration(groupKey+".stale_after", group.StaleAfter); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h Host) validate(key string) error {
+	if h.FQDN == "" && h.URL == "" {
+		return ConfigError{Key: key + ".fqdn", Message: "must not be blank"}
+	}
+	if h.URL != "" {
+		u, err := url.Parse(h.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return ConfigError{Key: key + ".url", Message: "must be an absolute http or https URL"}
+		}
+	}
+	if strings.ContainsAny(h.Name(), "/?#") {
+		return ConfigError{Key: key + ".alias", Message: fmt.Sprintf("%s cannot be used in a page URL", h.Name())}
+	}
+	if h.Interval < 0 {
+		return ConfigError{Key: key + ".interval", Message: "must not be negative"}
+	}
+	if h.Concurrency < 0 {
+		return ConfigError{Key: key + ".concurrency", Message: "must not be negative"}
+	}
+	for i, team := range h.Teams {
+		if team == "" {
+			return ConfigError{Key: fmt.Sprintf("%s.teams[%d]", key, i), Message: "must not be blank"}
+		}
+	}
+	if h.Auth != nil {
+		if err := h.Auth.validate(key + ".auth"); err != nil {
+			return err
+		}
+	}
+	if h.TLS != nil {
+		if err := h.TLS.validate(key + ".tls"); err != nil {
+			return err
+		}
+	}
+	for i, pipeline := range h.Pipelines {
+		if err := pipeline.validate(fmt.Sprintf("%s.pipelines[%d]", key, i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p Pipeline) validate(key string) error {
+	if p.Name == "" {
+		return ConfigError{Key: key + ".name", Message: "must not be blank"}
+	}
+	if _, err := compilePattern(p.Name); err != nil {
+		return ConfigError{Key: key + ".name", Message: err.Error()}
+	}
+	for i, group := range p.Groups {
+		if _, err := compilePattern(group); err != nil {
+			return ConfigError{Key: fmt.Sprintf("%s.groups[%d]", key, i), Message: err.Error()}
+		}
+	}
+	for i, exclude := range p.Exclude {
+		if _, err := compilePattern(exclude); err != nil {
+			return ConfigError{Key: fmt.Sprintf("%s.exclude[%d]", key, i), Message: err.Error()}
+		}
+	}
+	return nil
+}
+
+func (a *Auth) validate(key string) error {
+	switch {
+	case a.Token != "" && (a.Username != "" || a.Password != ""):
+		return ConfigError{Key: key, Message: "either token or username and password must be set, not both"}
+	case a.Token == "" && (a.Username == "" || a.Password == ""):
+		return ConfigError{Key: key, Message: "username and password must both be set when token is not"}
+	}
+	return nil
+}
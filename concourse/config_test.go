@@ -0,0 +1,437 @@
+package summary_test
+
+import (
+	"io/ioutil"
+	"os"
+	"regexp"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/FidelityInternational/go-concourse-summary/concourse"
+)
+
+var _ = Describe("#SetupConfigFromFile", func() {
+	var (
+		config                                                                    *summary.Config
+		err                                                                       error
+		path, contents                                                            string
+		refreshInterval, groupsJSON, hostsJSON, skipSSLValidationString, teamName string
+	)
+
+	JustBeforeEach(func() {
+		file, tempErr := ioutil.TempFile("", "config")
+		Ω(tempErr).Should(BeNil())
+		_, tempErr = file.WriteString(contents)
+		Ω(tempErr).Should(BeNil())
+		file.Close()
+		path = file.Name()
+
+		config, err = summary.SetupConfigFromFile(path, refreshInterval, groupsJSON, hostsJSON, skipSSLValidationString, teamName)
+	})
+
+	AfterEach(func() {
+		os.Remove(path)
+		refreshInterval = ""
+		groupsJSON = ""
+		hostsJSON = ""
+		skipSSLValidationString = ""
+		teamName = ""
+	})
+
+	Context("when the file is YAML", func() {
+		BeforeEach(func() {
+			contents = `
+refresh_interval: 10
+skip_ssl_validation: true
+team: development
+show_build_stats: true
+hosts:
+- ci.example.com
+- fqdn: private.example.com
+  auth:
+    username: admin
+    password: secret
+groups:
+- group: test
+  hosts:
+  - fqdn: ci.example.com
+    pipelines:
+    - name: binary-builder
+      groups: [automated-builds]
+`
+		})
+
+		It("returns populated config", func() {
+			Ω(err).Should(BeNil())
+			Ω(config.RefreshInterval).Should(Equal(10))
+			Ω(config.SkipSSLValidation).Should(BeTrue())
+			Ω(config.Team).Should(Equal("development"))
+			Ω(config.Protocol).Should(Equal("https"))
+			Ω(config.ShowBuildStats).Should(BeTrue())
+			Ω(config.Hosts).Should(Equal([]summary.Host{
+				{FQDN: "ci.example.com"},
+				{FQDN: "private.example.com", Auth: &summary.Auth{Username: "admin", Password: "secret"}},
+			}))
+			Ω(config.CSGroups).Should(Equal(summary.CSGroups{
+				{
+					Group: "test",
+					Hosts: []summary.Host{
+						{
+							FQDN:      "ci.example.com",
+							Pipelines: []summary.Pipeline{{Name: "binary-builder", Groups: []string{"automated-builds"}}},
+						},
+					},
+				},
+			}))
+		})
+
+		Context("and environment values are set", func() {
+			BeforeEach(func() {
+				refreshInterval = "20"
+				hostsJSON = `["env.example.com"]`
+				teamName = "main"
+			})
+
+			It("overrides the file values", func() {
+				Ω(err).Should(BeNil())
+				Ω(config.RefreshInterval).Should(Equal(20))
+				Ω(config.Hosts).Should(Equal([]summary.Host{{FQDN: "env.example.com"}}))
+				Ω(config.Team).Should(Equal("main"))
+				Ω(config.CSGroups).Should(HaveLen(1))
+				Ω(config.SkipSSLValidation).Should(BeTrue())
+			})
+		})
+	})
+
+	Context("when the file is JSON", func() {
+		BeforeEach(func() {
+			contents = `{"hosts": ["ci.example.com"], "groups": [{"group": "test", "hosts": [{"fqdn": "ci.example.com"}]}]}`
+		})
+
+		It("returns populated config with defaults for missing values", func() {
+			Ω(err).Should(BeNil())
+			Ω(config.RefreshInterval).Should(Equal(30))
+			Ω(config.Team).Should(Equal("main"))
+			Ω(config.Hosts).Should(Equal([]summary.Host{{FQDN: "ci.example.com"}}))
+			Ω(config.CSGroups[0].Hosts).Should(Equal([]summary.Host{{FQDN: "ci.example.com"}}))
+		})
+	})
+
+	Context("when the file has an unknown key", func() {
+		BeforeEach(func() {
+			contents = `
+groups:
+- group: test
+  hosts:
+  - fqdn: ci.example.com
+    pipelines:
+    - nmae: typo
+`
+		})
+
+		It("returns an error naming the key", func() {
+			Ω(err).Should(MatchError(path + ": groups[0].hosts[0].pipelines[0].nmae: unknown key"))
+			Ω(config).Should(Equal(&summary.Config{}))
+		})
+	})
+
+	Context("when the file has a value of the wrong type", func() {
+		BeforeEach(func() {
+			contents = `refresh_interval: often`
+		})
+
+		It("returns an error naming the key", func() {
+			Ω(err).Should(MatchError(ContainSubstring(path + ": refresh_interval: cannot use string as int")))
+		})
+	})
+
+	Context("when a host has no fqdn", func() {
+		BeforeEach(func() {
+			contents = `
+groups:
+- group: test
+  hosts:
+  - pipelines: [{name: test}]
+`
+		})
+
+		It("returns an error naming the key", func() {
+			Ω(err).Should(MatchError(path + ": groups[0].hosts[0].fqdn: must not be blank"))
+		})
+	})
+
+	Context("when a host has a blank team", func() {
+		BeforeEach(func() {
+			contents = `
+hosts:
+- fqdn: ci.example.com
+  teams: [main, ""]
+`
+		})
+
+		It("returns an error naming the key", func() {
+			Ω(err).Should(MatchError(path + ": hosts[0].teams[1]: must not be blank"))
+		})
+	})
+
+	Context("when a host has incomplete auth", func() {
+		BeforeEach(func() {
+			contents = `
+hosts:
+- fqdn: ci.example.com
+  auth:
+    username: admin
+`
+		})
+
+		It("returns an error naming the key", func() {
+			Ω(err).Should(MatchError(path + ": hosts[0].auth: username and password must both be set when token is not"))
+		})
+	})
+
+	Context("when a host has an invalid url", func() {
+		BeforeEach(func() {
+			contents = `
+hosts:
+- url: ci.example.com/concourse
+`
+		})
+
+		It("returns an error naming the key", func() {
+			Ω(err).Should(MatchError(path + ": hosts[0].url: must be an absolute http or https URL"))
+		})
+	})
+
+	Context("when an alias is used for two urls", func() {
+		BeforeEach(func() {
+			contents = `
+hosts:
+- url: http://ci.example.com:8080/concourse
+  alias: ci
+groups:
+- group: test
+  hosts:
+  - fqdn: ci.example.com
+    alias: ci
+`
+		})
+
+		It("returns an error naming the key", func() {
+			Ω(err).Should(MatchError(path + ": groups[0].hosts[0]: alias ci is already used for http://ci.example.com:8080/concourse"))
+		})
+	})
+
+	Context("when an alias is used with two different settings", func() {
+		BeforeEach(func() {
+			contents = `
+hosts:
+- fqdn: ci.example.com
+  alias: ci
+  auth:
+    token: my-token
+groups:
+- group: test
+  hosts:
+  - fqdn: ci.example.com
+    alias: ci
+    interval: 60
+- group: other
+  hosts:
+  - fqdn: ci.example.com
+    alias: ci
+    interval: 30
+`
+		})
+
+		It("returns an error naming the later key", func() {
+			Ω(err).Should(MatchError(path + ": groups[1].hosts[0]: alias ci is already used by groups[0].hosts[0] with a different interval"))
+		})
+	})
+
+	Context("when an alias leaves out settings given by another entry", func() {
+		BeforeEach(func() {
+			contents = `
+hosts:
+- fqdn: ci.example.com
+  alias: ci
+  auth:
+    token: my-token
+groups:
+- group: test
+  hosts:
+  - fqdn: ci.example.com
+    alias: ci
+    interval: 60
+`
+		})
+
+		It("inherits them", func() {
+			Ω(err).Should(BeNil())
+		})
+	})
+
+	Context("when a host has a client certificate without a key", func() {
+		BeforeEach(func() {
+			contents = `
+hosts:
+- fqdn: ci.example.com
+  tls:
+    client_cert: /etc/summary/client.pem
+`
+		})
+
+		It("returns an error naming the key", func() {
+			Ω(err).Should(MatchError(path + ": hosts[0].tls: client_cert and client_key must both be set"))
+		})
+	})
+
+	Context("when a host has a CA bundle that does not exist", func() {
+		BeforeEach(func() {
+			contents = `
+hosts:
+- fqdn: ci.example.com
+  tls:
+    ca_cert: /does/not/exist.pem
+`
+		})
+
+		It("returns an error naming the key", func() {
+			Ω(err).Should(MatchError(path + ": hosts[0].tls.ca_cert: open /does/not/exist.pem: no such file or directory"))
+		})
+	})
+
+	Context("when a pipeline has an invalid pattern", func() {
+		BeforeEach(func() {
+			contents = `
+groups:
+- group: test
+  hosts:
+  - fqdn: ci.example.com
+    pipelines:
+    - name: app-pr-*
+      exclude: ["/app-pr-(/"]
+`
+		})
+
+		It("returns an error naming the key", func() {
+			Ω(err).Should(MatchError(path + ": groups[0].hosts[0].pipelines[0].exclude[0]: invalid regular expression /app-pr-(/: error parsing regexp: missing closing ): `app-pr-(`"))
+		})
+	})
+
+	Context("when a notification has an unknown type", func() {
+		BeforeEach(func() {
+			contents = `
+notifications:
+- url: https://hooks.example.com/summary
+  type: email
+`
+		})
+
+		It("returns an error naming the key", func() {
+			Ω(err).Should(MatchError(path + ": notifications[0].type: email is not webhook or slack"))
+		})
+	})
+
+	Context("when a group notification has an invalid URL", func() {
+		BeforeEach(func() {
+			contents = `
+groups:
+- group: test
+  notifications:
+  - url: hooks.example.com
+    type: slack
+`
+		})
+
+		It("returns an error naming the key", func() {
+			Ω(err).Should(MatchError(path + ": groups[0].notifications[0].url: must be an absolute http or https URL"))
+		})
+	})
+
+	Context("when history has an unknown store", func() {
+		BeforeEach(func() {
+			contents = `
+history:
+  store: sql
+`
+		})
+
+		It("returns an error naming the key", func() {
+			Ω(err).Should(MatchError(path + ": history.store: sql is not memory or file"))
+		})
+	})
+
+	Context("when history has an invalid retention", func() {
+		BeforeEach(func() {
+			contents = `
+history:
+  store: file
+  path: /var/lib/summary/history.jsonl
+  retention: a week
+`
+		})
+
+		It("returns an error naming the key", func() {
+			Ω(err).Should(MatchError(path + ": history.retention: a week is not a positive duration"))
+		})
+	})
+
+	Context("when a group has an invalid stale_after", func() {
+		BeforeEach(func() {
+			contents = `
+stale_after: 336h
+groups:
+- group: test
+  stale_after: -1h
+`
+		})
+
+		It("returns an error naming the key", func() {
+			Ω(err).Should(MatchError(path + ": groups[0].stale_after: -1h is not a positive duration"))
+		})
+	})
+
+	Context("when a group is defined twice", func() {
+		BeforeEach(func() {
+			contents = `
+groups:
+- group: test
+- group: test
+`
+		})
+
+		It("returns an error naming the key", func() {
+			Ω(err).Should(MatchError(path + ": groups[1].group: group test is defined more than once"))
+		})
+	})
+
+	Context("when the file is not valid YAML", func() {
+		BeforeEach(func() {
+			contents = "hosts: [}"
+		})
+
+		It("returns an error", func() {
+			Ω(err).Should(HaveOccurred())
+			Ω(err.Error()).Should(HavePrefix(path + ": yaml:"))
+		})
+	})
+
+	Context("when the file does not exist", func() {
+		It("returns an error", func() {
+			_, err := summary.SetupConfigFromFile("/does/not/exist.yml", "", "", "", "", "")
+			Ω(err).Should(HaveOccurred())
+		})
+	})
+})
+
+var _ = Describe("The README", func() {
+	It("has an authentication example that loads", func() {
+		readme, err := ioutil.ReadFile("../README.md")
+		Ω(err).Should(BeNil())
+		example := regexp.MustCompile("(?s)#### Authentication.*?```\nHOSTS='(.*?)'\nCS_GROUPS='(.*?)'\n```").FindSubmatch(readme)
+		Ω(example).ShouldNot(BeNil())
+		_, err = summary.SetupConfig("", string(example[2]), string(example[1]), "", "")
+		Ω(err).Should(BeNil())
+	})
+})
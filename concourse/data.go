@@ -1,97 +1,216 @@
 package summary
 
 import (
-	"crypto/tls"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"sort"
+	"sync"
 	"time"
 
+	"github.com/concourse/atc"
 	"github.com/concourse/go-concourse/concourse"
 )
 
 // Data concourse data structure
 type Data struct {
-	Pipeline       string
-	Group          string
-	URL            string `json:"pipeline_url"`
-	Running        bool
-	Paused         bool
-	BrokenResource bool
-	Statuses       map[string]int
+	Team            string           `json:"team"`
+	Pipeline        string           `json:"pipeline"`
+	InstanceVars    InstanceVars     `json:"instance_vars,omitempty"`
+	Group           string           `json:"group"`
+	URL             string           `json:"pipeline_url"`
+	Running         bool             `json:"running"`
+	Paused          bool             `json:"paused"`
+	BrokenResource  bool             `json:"broken_resource"`
+	BrokenResources []BrokenResource `json:"broken_resources,omitempty"`
+	Statuses        map[string]int   `json:"statuses"`
+	Jobs            []Job            `json:"-"`
+	BuildStats      *BuildStats      `json:"build_stats,omitempty"`
+	// ShowBuildStats shows the build stats on the tile
+	ShowBuildStats bool `json:"-"`
+	// Stale is whether the pipeline group has not been built for longer than the threshold
+	Stale bool `json:"-"`
+	// StalledWorkers is the number of stalled workers of the host
+	StalledWorkers int `json:"-"`
+	// Since is when the pipeline group started failing or passing, zero when not known
+	Since time.Time `json:"-"`
+	// Trend is whether the pipeline group was failing or passing over recent periods, oldest first
+	Trend []string `json:"-"`
 }
 
 // GroupData a grouping structure for Data
 type GroupData struct {
-	Host     string
-	Statuses []Data
+	Host     string         `json:"host"`
+	Statuses []Data         `json:"statuses"`
+	Workers  *WorkerSummary `json:"workers,omitempty"`
+	Error    string         `json:"error,omitempty"`
 }
 
-func filterData(data []Data, pipelines []Pipeline) []Data {
+// filterTeams returns the data belonging to teams
+func filterTeams(data []Data, teams []string) []Data {
+	var filteredData []Data
+	for _, datum := range data {
+		for _, team := range teams {
+			if team == allTeams || team == datum.Team {
+				filteredData = append(filteredData, datum)
+				break
+			}
+		}
+	}
+	return filteredData
+}
+
+// filterData returns the data that belongs to any of pipelines, or all of it when there are none
+func filterData(data []Data, pipelines []Pipeline, patterns patterns) []Data {
 	var filteredData []Data
 	for _, datum := range data {
 		if len(pipelines) == 0 || pipelines == nil {
 			filteredData = append(filteredData, datum)
 		}
 		for _, pipeline := range pipelines {
-			if datum.Pipeline != pipeline.Name {
-				continue
-			}
-			if len(pipeline.Groups) == 0 || pipeline.Groups == nil {
+			if pipeline.matches(datum, patterns) {
 				filteredData = append(filteredData, datum)
-			} else {
-				for _, group := range pipeline.Groups {
-					if group == "all" && datum.Group == "" {
-						filteredData = append(filteredData, datum)
-					}
-					if group == datum.Group {
-						filteredData = append(filteredData, datum)
-					}
-				}
+				break
 			}
 		}
 	}
 	return filteredData
 }
 
-func getData(host string, config *Config) ([]Data, error) {
-	uri := fmt.Sprintf("%s://%s", config.Protocol, host)
-	webURI := uri + "/teams/" + config.Team + "/pipelines/"
-	httpClient := createHTTPClient(config)
-	client := concourse.NewClient(uri, httpClient, false)
-	team := client.Team(config.Team)
-	pipelines, err := team.ListPipelines()
-	if err != nil {
-		return []Data{}, err
+// matches reports whether data belongs to the pipeline. The name, groups and exclusions may be
+// globs or regular expressions, which are compiled into patterns when the config is loaded.
+func (p Pipeline) matches(datum Data, patterns patterns) bool {
+	if !patterns.match(p.Name, datum.Pipeline) || (p.Team != "" && datum.Team != p.Team) {
+		return false
 	}
-	data := map[string]Data{}
-	for _, pipeline := range pipelines {
-		jobs, err := team.ListJobs(pipeline.Name)
+	if !datum.InstanceVars.contains(p.InstanceVars) {
+		return false
+	}
+	if patterns.matchAny(p.Exclude, datum.Pipeline) {
+		return false
+	}
+	if len(p.Groups) == 0 {
+		return true
+	}
+	group := datum.Group
+	if group == "" {
+		group = "all"
+	}
+	return patterns.matchAny(p.Groups, group)
+}
+
+func getData(host Host, config *Config, metrics *Metrics) ([]Data, error) {
+	uri := host.baseURL(config)
+	teams := host.teams(config)
+	visibleOnly := false
+	for _, team := range teams {
+		if team == allTeams {
+			names, err := listTeams(uri, host, config, metrics)
+			if err != nil {
+				return []Data{}, err
+			}
+			teams, visibleOnly = names, true
+			break
+		}
+	}
+
+	var values []Data
+	for _, team := range teams {
+		data, err := getTeamData(uri, host, team, config, metrics)
+		if visibleOnly && (err == concourse.ErrUnauthorized || err == concourse.ErrForbidden) {
+			// when showing every team, those the credentials cannot see are left out
+			continue
+		}
 		if err != nil {
 			return []Data{}, err
 		}
-		for _, job := range jobs {
+		values = append(values, data...)
+	}
+	if values == nil {
+		values = []Data{}
+	}
+
+	sort.Sort(byData(values))
+
+	return values, nil
+}
+
+// listTeams returns the names of every team on a host, using the credentials of the configured team
+func listTeams(uri string, host Host, config *Config, metrics *Metrics) ([]string, error) {
+	client, err := authorisedClient(uri, host, config.Team, config, metrics)
+	if err != nil {
+		return nil, err
+	}
+	teams, err := client.listTeams()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(teams))
+	for _, team := range teams {
+		names = append(names, team.Name)
+	}
+	return names, nil
+}
+
+func getTeamData(uri string, host Host, teamName string, config *Config, metrics *Metrics) ([]Data, error) {
+	webURI := uri + "/teams/" + teamName + "/pipelines/"
+	client, err := authorisedClient(uri, host, teamName, config, metrics)
+	if err != nil {
+		return nil, err
+	}
+	pipelines, err := client.listPipelines(teamName)
+	if err == concourse.ErrUnauthorized && host.Auth != nil {
+		// the cached team token may have been revoked, so acquire a fresh one and retry once
+		tokens.invalidate(tokenKey(uri, teamName, host.Auth))
+		client, err = authorisedClient(uri, host, teamName, config, metrics)
+		if err != nil {
+			return nil, err
+		}
+		pipelines, err = client.listPipelines(teamName)
+	}
+	if err != nil {
+		return nil, err
+	}
+	details, err := listPipelineDetails(client, teamName, pipelines, host.concurrency())
+	if err != nil {
+		return nil, err
+	}
+	data := map[string]Data{}
+	for i, pipeline := range pipelines {
+		broken := brokenResources(details[i].resources)
+		pipelineURL := webURI + pipeline.Name
+		for _, job := range details[i].jobs {
 			groups := job.Groups
 			if len(groups) == 0 {
 				groups = []string{""}
 			}
 			for _, group := range groups {
-				key := fmt.Sprintf("%s:%s", pipeline.Name, group)
+				key := fmt.Sprintf("%s:%s:%s", pipeline.Name, pipeline.InstanceVars, group)
 				datum := data[key]
 				if datum.Statuses == nil {
 					datum.Statuses = map[string]int{}
+					datum.Team = teamName
 					datum.Pipeline = pipeline.Name
+					datum.InstanceVars = pipeline.InstanceVars
 					datum.Group = group
 					datum.Paused = pipeline.Paused
-					if group == "" {
-						datum.URL = fmt.Sprintf("%s%s", webURI, pipeline.Name)
-					} else {
-						datum.URL = fmt.Sprintf("%s%s?group=%s", webURI, pipeline.Name, group)
+					query := pipeline.InstanceVars.query()
+					if group != "" {
+						if query == nil {
+							query = url.Values{}
+						}
+						query.Set("group", group)
 					}
+					datum.URL = withQuery(pipelineURL, query)
 				}
 				if !datum.Running {
 					datum.Running = (job.NextBuild != nil)
 				}
+				for _, resource := range broken {
+					datum.addBrokenResource(resource)
+				}
+				datum.Jobs = append(datum.Jobs, newJob(job, teamName, pipeline, pipelineURL))
 				if job.FinishedBuild != nil {
 					datum.Statuses[job.FinishedBuild.Status]++
 				} else {
@@ -103,12 +222,48 @@ func getData(host string, config *Config) ([]Data, error) {
 	}
 	values := make([]Data, 0, len(data))
 	for _, value := range data {
+		value.BuildStats = newBuildStats(value.Jobs)
 		values = append(values, value)
 	}
+	return values, nil
+}
 
-	sort.Sort(byData(values))
+type pipelineDetails struct {
+	jobs      []atc.Job
+	resources []atc.Resource
+}
 
-	return values, nil
+// listPipelineDetails fetches the jobs and resources of each pipeline using at most concurrency
+// workers at a time, returning them in the same order as pipelines
+func listPipelineDetails(client *client, teamName string, pipelines []pipelineInstance, concurrency int) ([]pipelineDetails, error) {
+	var (
+		wg        sync.WaitGroup
+		details   = make([]pipelineDetails, len(pipelines))
+		errs      = make([]error, len(pipelines))
+		semaphore = make(chan struct{}, concurrency)
+	)
+	for i, pipeline := range pipelines {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(i int, p pipelineInstance) {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+			details[i].jobs, errs[i] = client.listJobs(teamName, p)
+			if errs[i] == nil {
+				details[i].resources, errs[i] = client.listResources(teamName, p)
+			}
+		}(i, pipeline)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return details, nil
 }
 
 // Percent calculate the a percentage value for a particular status from data statuses
@@ -119,6 +274,38 @@ func (d Data) Percent(status string) int {
 	return int((float64(d.Statuses[status]) / float64(mapValueSum(d.Statuses))) * 100)
 }
 
+// Key identifies the tile of a pipeline group on a page
+func (d Data) Key() string {
+	return fmt.Sprintf("%s/%s/%s/%s", d.Team, d.Pipeline, d.InstanceVars, d.Group)
+}
+
+// MarshalJSON adds the percentage of jobs in each status, and when known the time the pipeline
+// group entered its state, to the JSON representation of data
+func (d Data) MarshalJSON() ([]byte, error) {
+	type data Data
+	percentages := map[string]int{}
+	for status := range d.Statuses {
+		percentages[status] = d.Percent(status)
+	}
+	var since *time.Time
+	if !d.Since.IsZero() {
+		since = &d.Since
+	}
+	return json.Marshal(struct {
+		data
+		Percentages map[string]int `json:"percentages"`
+		Since       *time.Time     `json:"since,omitempty"`
+	}{data(d), percentages, since})
+}
+
+// withQuery appends query to uri when it is not empty
+func withQuery(uri string, query url.Values) string {
+	if len(query) == 0 {
+		return uri
+	}
+	return uri + "?" + query.Encode()
+}
+
 func mapValueSum(sourceData map[string]int) int {
 	sum := 0
 	for i := range sourceData {
@@ -127,16 +314,77 @@ func mapValueSum(sourceData map[string]int) int {
 	return sum
 }
 
-func createHTTPClient(config *Config) *http.Client {
+// transportCache keeps a transport for each host so that connections are reused between
+// collections rather than left open by a new transport each time. The transport of a host is
+// replaced when its TLS settings or the contents of its certificate files change.
+type transportCache struct {
+	sync.Mutex
+	transports map[string]cachedTransport
+}
+
+type cachedTransport struct {
+	settings  string
+	transport *http.Transport
+}
+
+var (
+	transports           = &transportCache{transports: map[string]cachedTransport{}}
+	transportIdleTimeout = 90 * time.Second
+)
+
+func (c *transportCache) get(config *Config, host Host) (*http.Transport, error) {
+	settings, err := json.Marshal(struct {
+		SkipSSLValidation bool
+		TLS               *TLS
+		Files             string
+	}{config.SkipSSLValidation, host.TLS, host.TLS.fingerprint()})
+	if err != nil {
+		return nil, err
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	cached, ok := c.transports[host.Name()]
+	if ok && cached.settings == string(settings) {
+		return cached.transport, nil
+	}
+	tlsConfig, err := host.tlsConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		cached.transport.CloseIdleConnections()
+	}
+	transport := &http.Transport{
+		MaxIdleConnsPerHost: 2,
+		IdleConnTimeout:     transportIdleTimeout,
+		TLSClientConfig:     tlsConfig,
+	}
+	c.transports[host.Name()] = cachedTransport{settings: string(settings), transport: transport}
+	return transport, nil
+}
+
+// forget closes the idle connections of the transport of a host and drops it
+func (c *transportCache) forget(host string) {
+	c.Lock()
+	defer c.Unlock()
+	if cached, ok := c.transports[host]; ok {
+		cached.transport.CloseIdleConnections()
+		delete(c.transports, host)
+	}
+}
+
+func createHTTPClient(config *Config, host Host) (*http.Client, error) {
+	transport, err := transports.get(config, host)
+	if err != nil {
+		return nil, err
+	}
 	client := &http.Client{
-		Transport: &http.Transport{
-			MaxIdleConnsPerHost: 2,
-			TLSClientConfig:     &tls.Config{InsecureSkipVerify: config.SkipSSLValidation},
-		},
-		Timeout: time.Duration(30) * time.Second,
+		Transport: transport,
+		Timeout:   time.Duration(30) * time.Second,
 	}
 
-	return client
+	return client, nil
 }
 
 type byData []Data
@@ -150,8 +398,11 @@ func (r byData) Swap(i, j int) {
 }
 
 func (r byData) Less(i, j int) bool {
-	first := fmt.Sprintf("%s%s", r[i].Pipeline, r[i].Group)
-	second := fmt.Sprintf("%s%s", r[j].Pipeline, r[j].Group)
+	if r[i].Team != r[j].Team {
+		return r[i].Team < r[j].Team
+	}
+	first := fmt.Sprintf("%s%s%s", r[i].Pipeline, r[i].InstanceVars, r[i].Group)
+	second := fmt.Sprintf("%s%s%s", r[j].Pipeline, r[j].InstanceVars, r[j].Group)
 
 	return first < second
 }
@@ -0,0 +1,208 @@
+package summary
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/vito/go-sse/sse"
+)
+
+var eventKeepAlive = 15 * time.Second
+
+// tileEvent is the data of an event replacing either a single tile, when Key is set, or every
+// tile of a host
+type tileEvent struct {
+	Host string `json:"host"`
+	Key  string `json:"key,omitempty"`
+	HTML string `json:"html"`
+}
+
+type updatedEvent struct {
+	Updated string `json:"updated"`
+}
+
+// sentTiles are the tiles of a host as last sent to a client
+type sentTiles struct {
+	err     string
+	workers string
+	keys    []string
+	tiles   map[string]string
+}
+
+// eventStream sends the changes to the tiles of a page as server-sent events
+type eventStream struct {
+	w         http.ResponseWriter
+	flusher   http.Flusher
+	id        int
+	sent      map[string]sentTiles
+	updatedAt time.Time
+}
+
+// HostEvents streams changes to the tiles of the host page as server-sent events, narrowed by
+// any filters in the query
+func (config *Config) HostEvents(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	host, ok := config.lookupHost(vars["host"])
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, "Host %s is not configured", vars["host"])
+		return
+	}
+	filter, err := parseQueryFilter(r.URL.Query())
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "Invalid filter %s", err.Error())
+		return
+	}
+
+	config.streamEvents(w, r, func(config *Config) ([]GroupData, time.Time) {
+		config.Collector.Watch(host)
+		snapshot, _ := config.Collector.Store.Get(host.Name())
+		if snapshot.Err != nil {
+			return []GroupData{{Host: host.Name(), Error: snapshot.Err.Error()}}, snapshot.UpdatedAt
+		}
+		statuses := config.tiles(host.Name(), filter.apply(snapshot.Data), config.staleAfter(nil))
+		return []GroupData{{Host: host.Name(), Statuses: statuses, Workers: snapshot.Workers}}, snapshot.UpdatedAt
+	})
+}
+
+// GroupEvents streams changes to the tiles of the group page as server-sent events
+func (config *Config) GroupEvents(w http.ResponseWriter, r *http.Request) {
+	group := mux.Vars(r)["group"]
+	if config.CSGroups.group(group).Group == "" {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, "Group %s is not configured", group)
+		return
+	}
+
+	config.streamEvents(w, r, func(config *Config) ([]GroupData, time.Time) {
+		return config.groupData(config.CSGroups.group(group))
+	})
+}
+
+// streamEvents sends the tiles returned by tiles whenever the collector collects a host, until
+// the client goes away. Tiles are read using the current config so that reloads are picked up.
+func (config *Config) streamEvents(w http.ResponseWriter, r *http.Request, tiles func(*Config) ([]GroupData, time.Time)) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprint(w, "Streaming is not supported")
+		return
+	}
+	collector := config.Collector
+	updates, unsubscribe := collector.Subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	stream := &eventStream{w: w, flusher: flusher, sent: map[string]sentTiles{}}
+	keepAlive := time.NewTicker(eventKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		current := collector.currentConfig()
+		groupsData, updatedAt := tiles(current)
+		if err := stream.send(current.Templates, groupsData, updatedAt); err != nil {
+			return
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-updates:
+		case <-keepAlive.C:
+			// a comment keeps proxies from closing the connection while nothing changes
+			if _, err := fmt.Fprint(w, ":\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// send sends the tiles that changed since they were last sent. A host whose tiles were added,
+// removed, reordered or replaced by an error, or whose workers changed, is sent as a whole.
+func (s *eventStream) send(templates *template.Template, groupsData []GroupData, updatedAt time.Time) error {
+	for _, groupData := range groupsData {
+		current := sentTiles{err: groupData.Error, tiles: map[string]string{}}
+		if groupData.Workers != nil {
+			html, err := render(templates, "workers", groupData.Workers)
+			if err != nil {
+				return err
+			}
+			current.workers = html
+		}
+		for _, datum := range groupData.Statuses {
+			html, err := render(templates, "tile", datum)
+			if err != nil {
+				return err
+			}
+			current.keys = append(current.keys, datum.Key())
+			current.tiles[datum.Key()] = html
+		}
+
+		previous, ok := s.sent[groupData.Host]
+		s.sent[groupData.Host] = current
+		if !ok || previous.err != current.err || previous.workers != current.workers || !equalKeys(previous.keys, current.keys) {
+			html, err := render(templates, "hostTiles", groupData)
+			if err != nil {
+				return err
+			}
+			if err := s.write("host", tileEvent{Host: groupData.Host, HTML: html}); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, key := range current.keys {
+			if previous.tiles[key] != current.tiles[key] {
+				if err := s.write("tile", tileEvent{Host: groupData.Host, Key: key, HTML: current.tiles[key]}); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if !updatedAt.Equal(s.updatedAt) {
+		s.updatedAt = updatedAt
+		header := headerStruct{UpdatedAt: updatedAt}
+		if err := s.write("updated", updatedEvent{Updated: header.Updated()}); err != nil {
+			return err
+		}
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+func (s *eventStream) write(name string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	s.id++
+	return sse.Event{ID: strconv.Itoa(s.id), Name: name, Data: data}.Write(s.w)
+}
+
+func render(templates *template.Template, name string, value interface{}) (string, error) {
+	var buffer bytes.Buffer
+	err := templates.ExecuteTemplate(&buffer, name, value)
+	return buffer.String(), err
+}
+
+func equalKeys(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
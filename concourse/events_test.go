@@ -0,0 +1,157 @@
+package summary_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vito/go-sse/sse"
+
+	"github.com/FidelityInternational/go-concourse-summary/concourse"
+)
+
+type tileEvent struct {
+	Host string `json:"host"`
+	Key  string `json:"key"`
+	HTML string `json:"html"`
+}
+
+var _ = Describe("Server-sent events", func() {
+	var (
+		concourse *fakeConcourse
+		appServer *httptest.Server
+		config    *summary.Config
+		host      summary.Host
+	)
+
+	setJobs := func(payload string) {
+		concourse.mock(jobsRoute("test1", payload))
+	}
+
+	// subscribe connects to an event stream and returns a channel receiving its events
+	subscribe := func(path string) (chan sse.Event, func()) {
+		resp, err := http.Get(appServer.URL + path)
+		Ω(err).Should(BeNil())
+		Ω(resp.StatusCode).Should(Equal(200))
+		Ω(resp.Header.Get("Content-Type")).Should(Equal("text/event-stream"))
+
+		events := make(chan sse.Event, 100)
+		reader := sse.NewReadCloser(resp.Body)
+		go func() {
+			defer close(events)
+			for {
+				event, err := reader.Next()
+				if err != nil {
+					return
+				}
+				events <- event
+			}
+		}()
+		return events, func() { reader.Close() }
+	}
+
+	// next returns the data of the next event named name, skipping any others
+	next := func(events chan sse.Event, name string) tileEvent {
+		for {
+			var event sse.Event
+			Eventually(events).Should(Receive(&event))
+			if event.Name == name {
+				var received tileEvent
+				Ω(json.Unmarshal(event.Data, &received)).Should(Succeed())
+				return received
+			}
+		}
+	}
+
+	BeforeEach(func() {
+		concourse = newFakeConcourse(pipelinesRoute(pipelinesPayload))
+		concourse.mock(pipelineRoutes(jobsPayload, "test1")...)
+		host = concourse.host("live")
+		config = pageConfig(host)
+		config.AllowAdHocHosts = false
+		config.CSGroups = summary.CSGroups{{Group: "wallboard", Hosts: []summary.Host{host}}}
+		appServer = httptest.NewServer(Router(config))
+		Ω(config.Collector.Collect(host).Err).Should(BeNil())
+	})
+
+	AfterEach(func() {
+		appServer.Close()
+		config.Collector.Stop()
+		concourse.Close()
+	})
+
+	Describe("/events/host/{host}", func() {
+		It("sends the tiles of the host and then the tiles that change", func() {
+			events, stop := subscribe("/events/host/live")
+			defer stop()
+
+			event := next(events, "host")
+			Ω(event.Host).Should(Equal("live"))
+			Ω(stringMinifier(event.HTML)).Should(ContainSubstring(`<adata-key="main/test1//"`))
+			Ω(stringMinifier(event.HTML)).Should(ContainSubstring(`<divclass="failed"style="width:16%;"></div>`))
+
+			setJobs(failedJobPayload)
+			config.Collector.Collect(host)
+
+			event = next(events, "tile")
+			Ω(event.Host).Should(Equal("live"))
+			Ω(event.Key).Should(Equal("main/test1//"))
+			Ω(stringMinifier(event.HTML)).Should(ContainSubstring(`<divclass="failed"style="width:100%;"></div>`))
+		})
+
+		It("sends the error tile when the host cannot be collected", func() {
+			events, stop := subscribe("/events/host/live")
+			defer stop()
+			next(events, "host")
+
+			setJobs("{")
+			config.Collector.Collect(host)
+
+			event := next(events, "host")
+			Ω(event.HTML).Should(ContainSubstring(`class="outer error"`))
+		})
+
+		It("applies filters", func() {
+			events, stop := subscribe("/events/host/live?pipeline=other")
+			defer stop()
+
+			event := next(events, "host")
+			Ω(event.HTML).ShouldNot(ContainSubstring("data-key"))
+		})
+
+		It("returns not found for an unknown host", func() {
+			resp, err := http.Get(appServer.URL + "/events/host/unknown")
+			Ω(err).Should(BeNil())
+			resp.Body.Close()
+			Ω(resp.StatusCode).Should(Equal(404))
+		})
+
+		It("rejects an invalid filter", func() {
+			resp, err := http.Get(appServer.URL + "/events/host/live?paused=maybe")
+			Ω(err).Should(BeNil())
+			resp.Body.Close()
+			Ω(resp.StatusCode).Should(Equal(400))
+		})
+	})
+
+	Describe("/events/group/{group}", func() {
+		It("sends the tiles of each host in the group", func() {
+			events, stop := subscribe("/events/group/wallboard")
+			defer stop()
+
+			event := next(events, "host")
+			Ω(event.Host).Should(Equal("live"))
+			Ω(event.HTML).Should(ContainSubstring(`data-key="main/test1//"`))
+		})
+
+		It("returns not found for an unknown group", func() {
+			resp, err := http.Get(appServer.URL + "/events/group/unknown")
+			Ω(err).Should(BeNil())
+			resp.Body.Close()
+			Ω(resp.StatusCode).Should(Equal(404))
+		})
+	})
+})
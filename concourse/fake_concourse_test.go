@@ -2,14 +2,18 @@ package summary_test
 
 import (
 	"fmt"
+	"html/template"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"strings"
+	"sync"
 
 	"github.com/gorilla/mux"
 	"github.com/onsi/ginkgo"
+
+	"github.com/FidelityInternational/go-concourse-summary/concourse"
 )
 
 var (
@@ -58,9 +62,25 @@ func setup(mock MockRoute) {
 	setupMultiple([]MockRoute{mock})
 }
 
+// legacyInfoPayload is the version of concourse that the mocked payloads were taken from
+const legacyInfoPayload = `{"version": "3.14.1", "worker_version": "2.1"}`
+
 func setupMultiple(mockEndpoints []MockRoute) {
 	router := mux.NewRouter()
 
+	mockedInfo, mockedWorkers := false, false
+	for _, mock := range mockEndpoints {
+		mockedInfo = mockedInfo || mock.Endpoint == "/api/v1/info"
+		mockedWorkers = mockedWorkers || mock.Endpoint == "/api/v1/workers"
+	}
+	if !mockedInfo {
+		mockEndpoints = append(mockEndpoints, MockRoute{"GET", "/api/v1/info", legacyInfoPayload, 200, "", nil})
+	}
+	if !mockedWorkers {
+		// workers that cannot be listed are left off the page
+		mockEndpoints = append(mockEndpoints, MockRoute{"GET", "/api/v1/workers", "not found", 404, "", nil})
+	}
+
 	for _, mock := range mockEndpoints {
 		method := mock.Method
 		endpoint := mock.Endpoint
@@ -97,3 +117,100 @@ func teardown() {
 	server.Close()
 	server = nil
 }
+
+// fakeConcourse is a mock concourse whose routes can be replaced while it is being collected,
+// eg, when the builds of a pipeline start failing. Like setupMultiple it serves the info of a
+// legacy concourse and no workers unless they are mocked, and fails on routes that are not.
+type fakeConcourse struct {
+	*httptest.Server
+	mu       sync.Mutex
+	routes   map[string]MockRoute
+	requests map[string]int
+}
+
+func newFakeConcourse(mocks ...MockRoute) *fakeConcourse {
+	fake := &fakeConcourse{routes: map[string]MockRoute{}, requests: map[string]int{}}
+	fake.mock(
+		MockRoute{"GET", "/api/v1/info", legacyInfoPayload, 200, "", nil},
+		MockRoute{"GET", "/api/v1/workers", "not found", 404, "", nil},
+	)
+	fake.mock(mocks...)
+	fake.Server = httptest.NewServer(http.HandlerFunc(fake.serve))
+	return fake
+}
+
+// mock replaces the routes with the methods and endpoints of mocks
+func (f *fakeConcourse) mock(mocks ...MockRoute) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, mock := range mocks {
+		f.routes[mock.Method+" "+mock.Endpoint] = mock
+	}
+}
+
+func (f *fakeConcourse) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	mock, ok := f.routes[r.Method+" "+r.URL.Path]
+	f.requests[r.URL.Path]++
+	f.mu.Unlock()
+	if !ok {
+		defer ginkgo.GinkgoRecover()
+		ginkgo.Fail(fmt.Sprintf("Route requested but not mocked: %s", r.URL))
+		return
+	}
+	testQueryString(r.URL.RawQuery, mock.QueryString)
+	testPostQuery(r, mock.PostForm)
+	w.WriteHeader(mock.Status)
+	fmt.Fprint(w, mock.Output)
+}
+
+// requested returns how many times endpoint has been requested
+func (f *fakeConcourse) requested(endpoint string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests[endpoint]
+}
+
+// host returns the fake concourse as a host addressed by alias
+func (f *fakeConcourse) host(alias string) summary.Host {
+	return summary.Host{URL: f.URL, Alias: alias}
+}
+
+func pipelinesRoute(pipelines string) MockRoute {
+	return MockRoute{"GET", "/api/v1/teams/main/pipelines", pipelines, 200, "", nil}
+}
+
+func jobsRoute(pipeline, jobs string) MockRoute {
+	return MockRoute{"GET", "/api/v1/teams/main/pipelines/" + pipeline + "/jobs", jobs, 200, "", nil}
+}
+
+func resourcesRoute(pipeline, resources string) MockRoute {
+	return MockRoute{"GET", "/api/v1/teams/main/pipelines/" + pipeline + "/resources", resources, 200, "", nil}
+}
+
+// pipelineRoutes returns the routes of pipelines of the main team that have jobs and no broken
+// resources
+func pipelineRoutes(jobs string, pipelines ...string) []MockRoute {
+	var routes []MockRoute
+	for _, pipeline := range pipelines {
+		routes = append(routes, jobsRoute(pipeline, jobs), resourcesRoute(pipeline, "[]"))
+	}
+	return routes
+}
+
+// pageConfig returns the config of a summary of hosts that renders its pages, and that only
+// collects when a test does
+func pageConfig(hosts ...summary.Host) *summary.Config {
+	config := buildConfig(template.Must(template.ParseGlob("../templates/*")), "main", "http")
+	config.RefreshInterval = 3600
+	config.Hosts = hosts
+	return config
+}
+
+// get serves a request for path from router
+func get(router *mux.Router, path string) *httptest.ResponseRecorder {
+	mockRecorder := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "http://example.com"+path, nil)
+	router.ServeHTTP(mockRecorder, req)
+	return mockRecorder
+}
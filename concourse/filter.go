@@ -0,0 +1,168 @@
+package summary
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// pattern matches names either as a glob, eg, app-pr-*, or as a regular expression when
+// wrapped in slashes, eg, /^app-pr-[0-9]+$/
+type pattern struct {
+	glob   string
+	regexp *regexp.Regexp
+}
+
+func compilePattern(value string) (pattern, error) {
+	if len(value) > 1 && strings.HasPrefix(value, "/") && strings.HasSuffix(value, "/") {
+		re, err := regexp.Compile(value[1 : len(value)-1])
+		if err != nil {
+			return pattern{}, fmt.Errorf("invalid regular expression %s: %s", value, err.Error())
+		}
+		return pattern{regexp: re}, nil
+	}
+	if _, err := path.Match(value, ""); err != nil {
+		return pattern{}, fmt.Errorf("invalid glob %s: %s", value, err.Error())
+	}
+	return pattern{glob: value}, nil
+}
+
+func (p pattern) match(name string) bool {
+	if p.regexp != nil {
+		return p.regexp.MatchString(name)
+	}
+	matched, _ := path.Match(p.glob, name)
+	return matched
+}
+
+// patterns are compiled patterns keyed by the values they were compiled from
+type patterns map[string]pattern
+
+// add compiles the name, group and exclude patterns of pipelines
+func (ps patterns) add(pipelines []Pipeline) error {
+	for _, pipeline := range pipelines {
+		if err := ps.compile(append(append([]string{pipeline.Name}, pipeline.Groups...), pipeline.Exclude...)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ps patterns) compile(values []string) error {
+	for _, value := range values {
+		if _, ok := ps[value]; ok {
+			continue
+		}
+		p, err := compilePattern(value)
+		if err != nil {
+			return err
+		}
+		ps[value] = p
+	}
+	return nil
+}
+
+// match reports whether name matches the pattern compiled from value. Values that were not
+// added are compiled as they are matched, and match nothing when they do not compile.
+func (ps patterns) match(value, name string) bool {
+	p, ok := ps[value]
+	if !ok {
+		var err error
+		if p, err = compilePattern(value); err != nil {
+			return false
+		}
+	}
+	return p.match(name)
+}
+
+// matchAny reports whether name matches the pattern compiled from any of values
+func (ps patterns) matchAny(values []string, name string) bool {
+	for _, value := range values {
+		if ps.match(value, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// queryFilter narrows the data of a host page using the query parameters of its URL
+type queryFilter struct {
+	pipelines []Pipeline
+	patterns  patterns
+	teams     []string
+	statuses  []string
+	paused    *bool
+}
+
+// parseQueryFilter reads the pipeline, group, exclude, team, status and paused query parameters.
+// Each may be given more than once, in which case data matching any of the values is kept. The
+// pipeline, group and exclude parameters are matched as the pipelines of a host are.
+func parseQueryFilter(query url.Values) (queryFilter, error) {
+	filter := queryFilter{patterns: patterns{}}
+	names := query["pipeline"]
+	if len(names) == 0 {
+		names = []string{"*"}
+	}
+	if err := filter.patterns.compile(names); err != nil {
+		return filter, fmt.Errorf("pipeline: %s", err.Error())
+	}
+	if err := filter.patterns.compile(query["group"]); err != nil {
+		return filter, fmt.Errorf("group: %s", err.Error())
+	}
+	if err := filter.patterns.compile(query["exclude"]); err != nil {
+		return filter, fmt.Errorf("exclude: %s", err.Error())
+	}
+	for _, name := range names {
+		filter.pipelines = append(filter.pipelines, Pipeline{Name: name, Groups: query["group"], Exclude: query["exclude"]})
+	}
+	filter.teams = query["team"]
+	filter.statuses = query["status"]
+	if value := query.Get("paused"); value != "" {
+		paused, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, fmt.Errorf("paused: %s is not true or false", value)
+		}
+		filter.paused = &paused
+	}
+	return filter, nil
+}
+
+func (f queryFilter) apply(data []Data) []Data {
+	filteredData := []Data{}
+	for _, datum := range filterData(data, f.pipelines, f.patterns) {
+		if f.matches(datum) {
+			filteredData = append(filteredData, datum)
+		}
+	}
+	return filteredData
+}
+
+func (f queryFilter) matches(datum Data) bool {
+	switch {
+	case len(f.teams) > 0 && !contains(f.teams, datum.Team):
+		return false
+	case f.paused != nil && datum.Paused != *f.paused:
+		return false
+	}
+	if len(f.statuses) == 0 {
+		return true
+	}
+	for _, status := range f.statuses {
+		if datum.Statuses[status] > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
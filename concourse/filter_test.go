@@ -0,0 +1,181 @@
+package summary_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/gorilla/mux"
+
+	"github.com/FidelityInternational/go-concourse-summary/concourse"
+)
+
+const failedJobPayload = `[
+  {
+    "id": 1,
+    "name": "failingJob",
+    "paused": false,
+    "team_name": "main",
+    "finished_build": {
+      "id": 1,
+      "name": "3",
+      "status": "failed"
+    }
+  }
+]`
+
+var _ = Describe("Host page filters", func() {
+	var (
+		config *summary.Config
+		router *mux.Router
+	)
+
+	request := func(path string) *httptest.ResponseRecorder {
+		mockRecorder := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "http://example.com"+strings.Replace(path, "HOST", Host(server), 1), nil)
+		router.ServeHTTP(mockRecorder, req)
+		return mockRecorder
+	}
+
+	pipelines := func(query string) []string {
+		mockRecorder := request("/api/v1/host/HOST?" + query)
+		Ω(mockRecorder.Code).Should(Equal(200))
+		var data []summary.Data
+		Ω(json.Unmarshal(mockRecorder.Body.Bytes(), &data)).Should(Succeed())
+		var names []string
+		for _, datum := range data {
+			names = append(names, fmt.Sprintf("%s:%s", datum.Pipeline, datum.Group))
+		}
+		return names
+	}
+
+	BeforeEach(func() {
+		mocks := []MockRoute{
+			{"GET", "/api/v1/teams/main/pipelines", multiplePipelinesPayload, 200, "", nil},
+			{"GET", "/api/v1/teams/main/pipelines/pipeline-a/jobs", jobsPayload, 200, "", nil},
+			{"GET", "/api/v1/teams/main/pipelines/pipeline-b/jobs", examplePipelineJobs, 200, "", nil},
+			{"GET", "/api/v1/teams/main/pipelines/pipeline-c/jobs", failedJobPayload, 200, "", nil},
+		}
+		for _, pipeline := range []string{"pipeline-a", "pipeline-b", "pipeline-c"} {
+			mocks = append(mocks, MockRoute{"GET", "/api/v1/teams/main/pipelines/" + pipeline + "/resources", "[]", 200, "", nil})
+		}
+		setupMultiple(mocks)
+		config = buildConfig(template.Must(template.ParseGlob("../templates/*")), "main", "http")
+		config.Hosts = []summary.Host{{FQDN: Host(server)}}
+		router = Router(config)
+		Ω(config.Collector.Collect(config.Hosts[0]).Err).Should(BeNil())
+	})
+
+	AfterEach(func() {
+		config.Collector.Stop()
+		teardown()
+	})
+
+	Describe("/api/v1/host/{host}", func() {
+		It("returns everything without filters", func() {
+			Ω(pipelines("")).Should(Equal([]string{"pipeline-a:", "pipeline-b:test-group", "pipeline-c:"}))
+		})
+
+		It("filters pipelines by glob", func() {
+			Ω(pipelines("pipeline=pipeline-[ab]")).Should(Equal([]string{"pipeline-a:", "pipeline-b:test-group"}))
+		})
+
+		It("filters pipelines by regular expression", func() {
+			Ω(pipelines("pipeline=/^pipeline-(b|c)$/")).Should(Equal([]string{"pipeline-b:test-group", "pipeline-c:"}))
+		})
+
+		It("filters by group, with all matching pipelines without groups", func() {
+			Ω(pipelines("group=test-group")).Should(Equal([]string{"pipeline-b:test-group"}))
+			Ω(pipelines("group=all")).Should(Equal([]string{"pipeline-a:", "pipeline-c:"}))
+		})
+
+		It("excludes pipelines", func() {
+			Ω(pipelines("exclude=*-a")).Should(Equal([]string{"pipeline-b:test-group", "pipeline-c:"}))
+		})
+
+		It("filters by status", func() {
+			Ω(pipelines("status=failed")).Should(Equal([]string{"pipeline-a:", "pipeline-c:"}))
+		})
+
+		It("filters by paused", func() {
+			Ω(pipelines("paused=false")).Should(Equal([]string{"pipeline-b:test-group", "pipeline-c:"}))
+		})
+
+		It("combines filters", func() {
+			Ω(pipelines("status=failed&paused=false")).Should(Equal([]string{"pipeline-c:"}))
+		})
+
+		It("rejects an invalid regular expression", func() {
+			mockRecorder := request("/api/v1/host/HOST?pipeline=" + url.QueryEscape("/[/"))
+			Ω(mockRecorder.Code).Should(Equal(400))
+			Ω(mockRecorder.Body.String()).Should(ContainSubstring(`"error":"pipeline: invalid regular expression /[/`))
+		})
+
+		It("rejects an invalid paused value", func() {
+			mockRecorder := request("/api/v1/host/HOST?paused=maybe")
+			Ω(mockRecorder.Code).Should(Equal(400))
+			Ω(mockRecorder.Body.String()).Should(MatchJSON(`{"error": "paused: maybe is not true or false"}`))
+		})
+	})
+
+	Describe("/host/{host}", func() {
+		It("only renders the matching tiles", func() {
+			mockRecorder := request("/host/HOST?status=failed&paused=false")
+			Ω(mockRecorder.Code).Should(Equal(200))
+			Ω(strings.Count(mockRecorder.Body.String(), `class="outer`)).Should(Equal(1))
+			Ω(mockRecorder.Body.String()).Should(ContainSubstring("pipeline-c"))
+		})
+
+		It("rejects an invalid filter", func() {
+			mockRecorder := request("/host/HOST?group=" + url.QueryEscape("[a"))
+			Ω(mockRecorder.Code).Should(Equal(400))
+			Ω(mockRecorder.Body.String()).Should(Equal("Invalid filter group: invalid glob [a: syntax error in pattern"))
+		})
+	})
+
+	Describe("/api/v1/group/{group}", func() {
+		var groupPipelines func(pipelines ...summary.Pipeline) []string
+
+		BeforeEach(func() {
+			groupPipelines = func(pipelines ...summary.Pipeline) []string {
+				config.CSGroups = summary.CSGroups{{Group: "test", Hosts: []summary.Host{{FQDN: Host(server), Pipelines: pipelines}}}}
+				mockRecorder := request("/api/v1/group/test")
+				Ω(mockRecorder.Code).Should(Equal(200))
+				var groups []summary.GroupData
+				Ω(json.Unmarshal(mockRecorder.Body.Bytes(), &groups)).Should(Succeed())
+				var names []string
+				for _, datum := range groups[0].Statuses {
+					names = append(names, fmt.Sprintf("%s:%s", datum.Pipeline, datum.Group))
+				}
+				return names
+			}
+		})
+
+		It("matches pipeline names by glob", func() {
+			Ω(groupPipelines(summary.Pipeline{Name: "pipeline-*"})).Should(Equal([]string{"pipeline-a:", "pipeline-b:test-group", "pipeline-c:"}))
+		})
+
+		It("matches pipeline names by regular expression", func() {
+			Ω(groupPipelines(summary.Pipeline{Name: "/-[ac]$/"})).Should(Equal([]string{"pipeline-a:", "pipeline-c:"}))
+		})
+
+		It("matches groups by pattern", func() {
+			Ω(groupPipelines(summary.Pipeline{Name: "pipeline-*", Groups: []string{"test-*"}})).Should(Equal([]string{"pipeline-b:test-group"}))
+		})
+
+		It("leaves out excluded pipelines", func() {
+			Ω(groupPipelines(summary.Pipeline{Name: "pipeline-*", Exclude: []string{"*-b", "/c$/"}})).Should(Equal([]string{"pipeline-a:"}))
+		})
+
+		It("shows pipelines matching more than one entry once", func() {
+			Ω(groupPipelines(summary.Pipeline{Name: "pipeline-a"}, summary.Pipeline{Name: "pipeline-*"})).Should(Equal([]string{"pipeline-a:", "pipeline-b:test-group", "pipeline-c:"}))
+		})
+	})
+})
@@ -0,0 +1,489 @@
+package summary
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	defaultHistoryRetention = 7 * 24 * time.Hour
+	defaultHistoryTrend     = 24 * time.Hour
+	historyPruneInterval    = time.Minute
+	trendBuckets            = 24
+)
+
+const (
+	historyMemory = "memory"
+	historyFile   = "file"
+
+	trendFailing = "failing"
+	trendPassing = "passing"
+	trendUnknown = "unknown"
+)
+
+// HistoryConfig configures how the states of pipeline groups are kept. Retention and Trend are
+// durations such as 72h.
+type HistoryConfig struct {
+	Store     string `json:"store"`
+	Path      string `json:"path"`
+	Retention string `json:"retention"`
+	Trend     string `json:"trend"`
+}
+
+// HistoryPoint is the state of a pipeline group from the collection at Time until the next point
+type HistoryPoint struct {
+	Host         string         `json:"host"`
+	Team         string         `json:"team"`
+	Pipeline     string         `json:"pipeline"`
+	InstanceVars InstanceVars   `json:"instance_vars,omitempty"`
+	Group        string         `json:"group"`
+	Time         time.Time      `json:"time"`
+	Failing      bool           `json:"failing"`
+	Statuses     map[string]int `json:"statuses"`
+}
+
+func (p HistoryPoint) key() string {
+	return p.Host + " " + p.groupKey()
+}
+
+func (p HistoryPoint) groupKey() string {
+	return Data{Team: p.Team, Pipeline: p.Pipeline, InstanceVars: p.InstanceVars, Group: p.Group}.Key()
+}
+
+// HistoryStore keeps the points recorded for pipeline groups. Stores other than those in this
+// package can be used by setting Config.HistoryStore.
+type HistoryStore interface {
+	// Append adds points, which are recorded in time order
+	Append(points []HistoryPoint) error
+	// Query returns the points of a host recorded at or after since, oldest first
+	Query(host string, since time.Time) ([]HistoryPoint, error)
+	// QueryGroup returns the points of the pipeline group of a host with a key, oldest first
+	QueryGroup(host, key string) ([]HistoryPoint, error)
+	// Prune removes the points recorded before a time, except the latest point of each pipeline
+	// group so that its current state is still known
+	Prune(before time.Time) error
+}
+
+// MemoryHistoryStore keeps history in memory, so that it is lost on restart
+type MemoryHistoryStore struct {
+	sync.RWMutex
+	// points are the points of each host by the key of their pipeline group
+	points map[string]map[string][]HistoryPoint
+}
+
+// NewMemoryHistoryStore - creates an empty in memory history store
+func NewMemoryHistoryStore() *MemoryHistoryStore {
+	return &MemoryHistoryStore{points: map[string]map[string][]HistoryPoint{}}
+}
+
+// Append adds points to the store
+func (s *MemoryHistoryStore) Append(points []HistoryPoint) error {
+	s.Lock()
+	defer s.Unlock()
+	for _, point := range points {
+		groups, ok := s.points[point.Host]
+		if !ok {
+			groups = map[string][]HistoryPoint{}
+			s.points[point.Host] = groups
+		}
+		groups[point.groupKey()] = append(groups[point.groupKey()], point)
+	}
+	return nil
+}
+
+// Query returns the points of a host recorded at or after since
+func (s *MemoryHistoryStore) Query(host string, since time.Time) ([]HistoryPoint, error) {
+	s.RLock()
+	defer s.RUnlock()
+	points := []HistoryPoint{}
+	for _, group := range s.points[host] {
+		for _, point := range group {
+			if !point.Time.Before(since) {
+				points = append(points, point)
+			}
+		}
+	}
+	sort.Slice(points, func(i, j int) bool {
+		if !points[i].Time.Equal(points[j].Time) {
+			return points[i].Time.Before(points[j].Time)
+		}
+		return points[i].key() < points[j].key()
+	})
+	return points, nil
+}
+
+// QueryGroup returns the points of the pipeline group of a host with a key
+func (s *MemoryHistoryStore) QueryGroup(host, key string) ([]HistoryPoint, error) {
+	s.RLock()
+	defer s.RUnlock()
+	return append([]HistoryPoint{}, s.points[host][key]...), nil
+}
+
+// Prune removes the points recorded before a time, except the latest of each pipeline group
+func (s *MemoryHistoryStore) Prune(before time.Time) error {
+	s.prune(before)
+	return nil
+}
+
+// prune removes the points recorded before a time, except the latest of each pipeline group,
+// returning how many were removed
+func (s *MemoryHistoryStore) prune(before time.Time) int {
+	s.Lock()
+	defer s.Unlock()
+	removed := 0
+	for _, groups := range s.points {
+		for key, points := range groups {
+			kept := pruned(points, before)
+			removed += len(points) - len(kept)
+			groups[key] = kept
+		}
+	}
+	return removed
+}
+
+// pruned returns the points of a pipeline group recorded at or after a time, or the latest point
+// when all of them were recorded before it
+func pruned(points []HistoryPoint, before time.Time) []HistoryPoint {
+	kept := len(points) - 1
+	for i, point := range points {
+		if !point.Time.Before(before) {
+			kept = i
+			break
+		}
+	}
+	if kept == 0 {
+		return points
+	}
+	return append([]HistoryPoint{}, points[kept:]...)
+}
+
+// FileHistoryStore keeps history in memory and appends it to a file of JSON lines, which is read
+// back when the store is opened so that history survives restarts
+type FileHistoryStore struct {
+	*MemoryHistoryStore
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileHistoryStore - opens a file history store, reading any points already in the file
+func NewFileHistoryStore(path string) (*FileHistoryStore, error) {
+	store := &FileHistoryStore{MemoryHistoryStore: NewMemoryHistoryStore(), path: path}
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var point HistoryPoint
+		if err := json.Unmarshal(scanner.Bytes(), &point); err != nil {
+			return nil, fmt.Errorf("%s:%d: %s", path, line, err.Error())
+		}
+		store.MemoryHistoryStore.Append([]HistoryPoint{point})
+	}
+	return store, scanner.Err()
+}
+
+// Append adds points to the store and the end of its file
+func (s *FileHistoryStore) Append(points []HistoryPoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	encoder := json.NewEncoder(file)
+	for _, point := range points {
+		if err := encoder.Encode(point); err != nil {
+			return err
+		}
+	}
+	return s.MemoryHistoryStore.Append(points)
+}
+
+// Prune removes the points recorded before a time, except the latest of each pipeline group, and
+// rewrites the file without them when any were removed
+func (s *FileHistoryStore) Prune(before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.MemoryHistoryStore.prune(before) == 0 {
+		return nil
+	}
+
+	temp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+	encoder := json.NewEncoder(temp)
+	s.RLock()
+	for _, groups := range s.points {
+		for _, points := range groups {
+			for _, point := range points {
+				if err := encoder.Encode(point); err != nil {
+					s.RUnlock()
+					temp.Close()
+					return err
+				}
+			}
+		}
+	}
+	s.RUnlock()
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), s.path)
+}
+
+// history records the points of pipeline groups whose state changed between collections
+type history struct {
+	store     HistoryStore
+	retention time.Duration
+	trend     time.Duration
+
+	mu       sync.Mutex
+	last     map[string]HistoryPoint
+	seeded   map[string]bool
+	prunedAt time.Time
+}
+
+// newHistory opens the store of config, returning nil when history is not configured. The
+// HistoryStore of config is used when it is set, otherwise the store named by History.
+func newHistory(config *Config) (*history, error) {
+	if config.History == nil && config.HistoryStore == nil {
+		return nil, nil
+	}
+	settings := HistoryConfig{}
+	if config.History != nil {
+		settings = *config.History
+	}
+	h := &history{retention: defaultHistoryRetention, trend: defaultHistoryTrend, last: map[string]HistoryPoint{}, seeded: map[string]bool{}}
+	if settings.Retention != "" {
+		h.retention, _ = time.ParseDuration(settings.Retention)
+	}
+	if settings.Trend != "" {
+		h.trend, _ = time.ParseDuration(settings.Trend)
+	}
+
+	switch {
+	case config.HistoryStore != nil:
+		h.store = config.HistoryStore
+	case settings.Store == historyFile:
+		store, err := NewFileHistoryStore(settings.Path)
+		if err != nil {
+			return nil, err
+		}
+		h.store = store
+	default:
+		h.store = NewMemoryHistoryStore()
+	}
+	return h, nil
+}
+
+// record appends a point for each pipeline group of a host whose statuses changed since it was
+// last recorded, and prunes points older than the retention now and then
+func (h *history) record(host string, snapshot Snapshot) {
+	if snapshot.Err != nil {
+		return
+	}
+	h.mu.Lock()
+	if !h.seeded[host] {
+		// the store may already hold points from before a restart, which need not be recorded again
+		h.seeded[host] = true
+		points, _ := h.store.Query(host, time.Time{})
+		for _, point := range points {
+			h.last[point.key()] = point
+		}
+	}
+	var points []HistoryPoint
+	for _, datum := range snapshot.Data {
+		point := HistoryPoint{
+			Host:         host,
+			Team:         datum.Team,
+			Pipeline:     datum.Pipeline,
+			InstanceVars: datum.InstanceVars,
+			Group:        datum.Group,
+			Time:         snapshot.UpdatedAt,
+			Failing:      failing(datum),
+			Statuses:     datum.Statuses,
+		}
+		if last, ok := h.last[point.key()]; ok && equalStatuses(last.Statuses, point.Statuses) {
+			continue
+		}
+		h.last[point.key()] = point
+		points = append(points, point)
+	}
+	prune := time.Since(h.prunedAt) >= historyPruneInterval
+	if prune {
+		h.prunedAt = time.Now()
+	}
+	h.mu.Unlock()
+
+	if len(points) > 0 {
+		if err := h.store.Append(points); err != nil {
+			fmt.Printf("Error recording history of concourse (%s): %s\n", host, err.Error())
+		}
+	}
+	if prune {
+		if err := h.store.Prune(time.Now().Add(-h.retention)); err != nil {
+			fmt.Printf("Error pruning history: %s\n", err.Error())
+		}
+	}
+}
+
+// trends sets the trend of each pipeline group of a host, returning a copy of data
+func (h *history) trends(host string, data []Data) []Data {
+	if h == nil || len(data) == 0 {
+		return data
+	}
+	now := time.Now()
+	trended := make([]Data, len(data))
+	for i, datum := range data {
+		points, err := h.store.QueryGroup(host, datum.Key())
+		if err != nil {
+			return data
+		}
+		datum.Trend = trend(points, now.Add(-h.trend), now)
+		trended[i] = datum
+	}
+	return trended
+}
+
+// trend divides the time from start to end into buckets, each failing when the pipeline group
+// was failing at any point during it, passing when it was only passing and unknown before the
+// first point
+func trend(points []HistoryPoint, start, end time.Time) []string {
+	buckets := make([]string, trendBuckets)
+	width := end.Sub(start) / time.Duration(trendBuckets)
+	for i := range buckets {
+		from := start.Add(time.Duration(i) * width)
+		to := from.Add(width)
+		buckets[i] = trendUnknown
+		for j, point := range points {
+			if !point.Time.Before(to) {
+				break
+			}
+			// a point applies until the next one
+			if j+1 < len(points) && !points[j+1].Time.After(from) {
+				continue
+			}
+			if point.Failing {
+				buckets[i] = trendFailing
+				break
+			}
+			buckets[i] = trendPassing
+		}
+	}
+	return buckets
+}
+
+func equalStatuses(a, b map[string]int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for status, count := range a {
+		if b[status] != count {
+			return false
+		}
+	}
+	return true
+}
+
+func (h HistoryConfig) validate(key string) error {
+	switch h.Store {
+	case "", historyMemory:
+	case historyFile:
+		if h.Path == "" {
+			return ConfigError{Key: key + ".path", Message: "must be set for the file store"}
+		}
+	default:
+		return ConfigError{Key: key + ".store", Message: fmt.Sprintf("%s is not memory or file", h.Store)}
+	}
+	if err := validateDuration(key+".retention", h.Retention); err != nil {
+		return err
+	}
+	return validateDuration(key+".trend", h.Trend)
+}
+
+func validateDuration(key, value string) error {
+	if value == "" {
+		return nil
+	}
+	if duration, err := time.ParseDuration(value); err != nil || duration <= 0 {
+		return ConfigError{Key: key, Message: fmt.Sprintf("%s is not a positive duration", value)}
+	}
+	return nil
+}
+
+// HistoryJSON serves the recorded states of the pipeline groups of a host, optionally narrowed
+// by the pipeline, group, team, vars and since query parameters
+func (config *Config) HistoryJSON(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	h := config.Collector.history()
+	if h == nil {
+		writeJSON(w, http.StatusNotFound, errorJSON{Error: "history is not enabled"}, time.Time{})
+		return
+	}
+	host, ok := config.lookupHost(query.Get("host"))
+	if !ok || query.Get("host") == "" {
+		writeJSON(w, http.StatusNotFound, errorJSON{Error: fmt.Sprintf("host %s is not configured", query.Get("host"))}, time.Time{})
+		return
+	}
+	since, err := parseSince(query.Get("since"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorJSON{Error: err.Error()}, time.Time{})
+		return
+	}
+	instanceVars, err := normalInstanceVars(query.Get("vars"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorJSON{Error: fmt.Sprintf("vars: %s", err.Error())}, time.Time{})
+		return
+	}
+
+	points, err := h.store.Query(host.Name(), since)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, errorJSON{Error: err.Error()}, time.Time{})
+		return
+	}
+	matching := []HistoryPoint{}
+	for _, point := range points {
+		switch {
+		case query.Get("pipeline") != "" && point.Pipeline != query.Get("pipeline"):
+		case query.Get("group") != "" && point.Group != query.Get("group"):
+		case query.Get("team") != "" && point.Team != query.Get("team"):
+		case instanceVars != "" && point.InstanceVars.String() != instanceVars:
+		default:
+			matching = append(matching, point)
+		}
+	}
+	writeJSON(w, http.StatusOK, matching, time.Time{})
+}
+
+// parseSince reads either a time in RFC 3339 format or a duration before now
+func parseSince(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if duration, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-duration), nil
+	}
+	since, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return since, fmt.Errorf("since: %s is not a time or duration", value)
+	}
+	return since, nil
+}
@@ -0,0 +1,220 @@
+package summary_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/gorilla/mux"
+
+	"github.com/FidelityInternational/go-concourse-summary/concourse"
+)
+
+var _ = Describe("History", func() {
+	var (
+		concourse *fakeConcourse
+		config    *summary.Config
+		router    *mux.Router
+		host      summary.Host
+	)
+
+	points := func(query string) []summary.HistoryPoint {
+		mockRecorder := get(router, "/api/v1/history?"+query)
+		Ω(mockRecorder.Code).Should(Equal(200))
+		var points []summary.HistoryPoint
+		Ω(json.Unmarshal(mockRecorder.Body.Bytes(), &points)).Should(Succeed())
+		return points
+	}
+
+	BeforeEach(func() {
+		concourse = newFakeConcourse(pipelinesRoute(multiplePipelinesPayload))
+		concourse.mock(pipelineRoutes(buildPayload("succeeded"), "pipeline-a", "pipeline-b", "pipeline-c")...)
+		host = concourse.host("ci")
+		config = pageConfig(host)
+	})
+
+	AfterEach(func() {
+		config.Collector.Stop()
+		concourse.Close()
+	})
+
+	Context("when history is not enabled", func() {
+		BeforeEach(func() {
+			router = Router(config)
+		})
+
+		It("returns not found from the history API", func() {
+			mockRecorder := get(router, "/api/v1/history?host=ci")
+			Ω(mockRecorder.Code).Should(Equal(404))
+			Ω(mockRecorder.Body.String()).Should(MatchJSON(`{"error": "history is not enabled"}`))
+		})
+
+		It("does not show trends on tiles", func() {
+			Ω(config.Collector.Collect(host).Err).Should(BeNil())
+			Ω(get(router, "/host/ci").Body.String()).ShouldNot(ContainSubstring(`class="trend"`))
+		})
+	})
+
+	Context("when history is kept in memory", func() {
+		BeforeEach(func() {
+			config.History = &summary.HistoryConfig{}
+			router = Router(config)
+			Ω(config.Collector.Collect(host).Err).Should(BeNil())
+			Ω(config.Collector.Collect(host).Err).Should(BeNil())
+			concourse.mock(jobsRoute("pipeline-a", buildPayload("failed")))
+			Ω(config.Collector.Collect(host).Err).Should(BeNil())
+		})
+
+		It("records the state of each pipeline group when it changes", func() {
+			history := points("host=ci&pipeline=pipeline-a")
+			Ω(history).Should(HaveLen(2))
+			Ω(history[0].Failing).Should(BeFalse())
+			Ω(history[0].Statuses).Should(Equal(map[string]int{"succeeded": 1}))
+			Ω(history[1].Failing).Should(BeTrue())
+			Ω(history[1].Time.After(history[0].Time)).Should(BeTrue())
+			Ω(points("host=ci")).Should(HaveLen(4))
+		})
+
+		It("filters by time", func() {
+			Ω(points("host=ci&since=" + time.Now().Add(time.Hour).Format(time.RFC3339))).Should(BeEmpty())
+			Ω(points("host=ci&pipeline=pipeline-a&since=1h")).Should(HaveLen(2))
+		})
+
+		It("rejects an invalid since", func() {
+			mockRecorder := get(router, "/api/v1/history?host=ci&since=yesterday")
+			Ω(mockRecorder.Code).Should(Equal(400))
+			Ω(mockRecorder.Body.String()).Should(MatchJSON(`{"error": "since: yesterday is not a time or duration"}`))
+		})
+
+		It("returns not found for an unknown host", func() {
+			config.AllowAdHocHosts = false
+			Ω(get(router, "/api/v1/history?host=unknown").Code).Should(Equal(404))
+		})
+
+		It("shows the trend on tiles", func() {
+			body := stringMinifier(get(router, "/host/ci").Body.String())
+			Ω(body).Should(ContainSubstring(`<divclass="trend">`))
+			Ω(body).Should(ContainSubstring(`<spanclass="unknown"></span><spanclass="failing"></span></div>`))
+			Ω(body).Should(ContainSubstring(`<spanclass="unknown"></span><spanclass="passing"></span></div>`))
+		})
+	})
+
+	Context("when history is kept in a store of the config", func() {
+		var store *summary.MemoryHistoryStore
+
+		BeforeEach(func() {
+			store = summary.NewMemoryHistoryStore()
+			config.HistoryStore = store
+			router = Router(config)
+			Ω(config.Collector.Collect(host).Err).Should(BeNil())
+		})
+
+		It("records to that store", func() {
+			history, err := store.Query("ci", time.Time{})
+			Ω(err).Should(BeNil())
+			Ω(history).Should(HaveLen(3))
+			Ω(points("host=ci")).Should(HaveLen(3))
+		})
+	})
+
+	Context("when history is kept in a file", func() {
+		var dir string
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "summary-history")
+			Ω(err).Should(BeNil())
+			config.History = &summary.HistoryConfig{Store: "file", Path: filepath.Join(dir, "history.jsonl")}
+			router = Router(config)
+			Ω(config.Collector.Collect(host).Err).Should(BeNil())
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+
+		It("reads the history back when it is reopened", func() {
+			store, err := summary.NewFileHistoryStore(config.History.Path)
+			Ω(err).Should(BeNil())
+			history, err := store.Query("ci", time.Time{})
+			Ω(err).Should(BeNil())
+			Ω(history).Should(HaveLen(3))
+		})
+
+		It("does not record unchanged states again after a restart", func() {
+			config.Collector.Stop()
+			router = Router(config)
+			Ω(config.Collector.Collect(host).Err).Should(BeNil())
+			contents, err := ioutil.ReadFile(config.History.Path)
+			Ω(err).Should(BeNil())
+			Ω(strings.Count(string(contents), "\n")).Should(Equal(3))
+		})
+	})
+})
+
+var _ = Describe("MemoryHistoryStore#Prune", func() {
+	It("removes old points but keeps the latest of each pipeline group", func() {
+		store := summary.NewMemoryHistoryStore()
+		old := time.Now().Add(-48 * time.Hour)
+		Ω(store.Append([]summary.HistoryPoint{
+			{Host: "ci", Pipeline: "a", Time: old, Failing: true},
+			{Host: "ci", Pipeline: "a", Time: old.Add(time.Hour)},
+			{Host: "ci", Pipeline: "b", Time: old},
+			{Host: "ci", Pipeline: "b", Time: time.Now()},
+		})).Should(Succeed())
+		Ω(store.Prune(time.Now().Add(-24 * time.Hour))).Should(Succeed())
+
+		history, err := store.Query("ci", time.Time{})
+		Ω(err).Should(BeNil())
+		Ω(history).Should(HaveLen(2))
+		Ω(history[0].Pipeline).Should(Equal("a"))
+		Ω(history[0].Failing).Should(BeFalse())
+		Ω(history[1].Pipeline).Should(Equal("b"))
+	})
+})
+
+var _ = Describe("FileHistoryStore#Prune", func() {
+	var (
+		dir   string
+		path  string
+		store *summary.FileHistoryStore
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "summary-history")
+		Ω(err).Should(BeNil())
+		path = filepath.Join(dir, "history.jsonl")
+		store, err = summary.NewFileHistoryStore(path)
+		Ω(err).Should(BeNil())
+		old := time.Now().Add(-48 * time.Hour)
+		Ω(store.Append([]summary.HistoryPoint{
+			{Host: "ci", Pipeline: "a", Time: old, Failing: true},
+			{Host: "ci", Pipeline: "a", Time: old.Add(time.Hour)},
+		})).Should(Succeed())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("rewrites the file without the removed points", func() {
+		Ω(store.Prune(time.Now().Add(-24 * time.Hour))).Should(Succeed())
+		contents, err := ioutil.ReadFile(path)
+		Ω(err).Should(BeNil())
+		Ω(strings.Count(string(contents), "\n")).Should(Equal(1))
+	})
+
+	It("does not rewrite the file when no points are removed", func() {
+		Ω(os.Remove(path)).Should(Succeed())
+		Ω(store.Prune(time.Now().Add(-72 * time.Hour))).Should(Succeed())
+		_, err := os.Stat(path)
+		Ω(os.IsNotExist(err)).Should(BeTrue())
+	})
+})
@@ -0,0 +1,72 @@
+package summary_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/FidelityInternational/go-concourse-summary/concourse"
+)
+
+var _ = Describe("Hosts with a URL and alias", func() {
+	var (
+		config *summary.Config
+		host   summary.Host
+	)
+
+	BeforeEach(func() {
+		mocks := []MockRoute{
+			{"GET", "/concourse/api/v1/info", legacyInfoPayload, 200, "", nil},
+			{"GET", "/concourse/api/v1/teams/main/pipelines", pipelinesPayload, 200, "", nil},
+			{"GET", "/concourse/api/v1/teams/main/pipelines/test1/jobs", jobsPayload, 200, "", nil},
+			{"GET", "/concourse/api/v1/teams/main/pipelines/test1/resources", "[]", 200, "", nil},
+		}
+		setupMultiple(mocks)
+		config = buildConfig(nil, "main", "https")
+		host = summary.Host{URL: server.URL + "/concourse/", Alias: "local"}
+		config.Hosts = []summary.Host{host}
+	})
+
+	AfterEach(func() {
+		teardown()
+	})
+
+	It("is named by its alias", func() {
+		Ω(host.Name()).Should(Equal("local"))
+		Ω(summary.Host{URL: server.URL + "/concourse"}.Name()).Should(Equal(Host(server)))
+		Ω(summary.Host{FQDN: "ci.example.com"}.Name()).Should(Equal("ci.example.com"))
+	})
+
+	Describe("Collector#Collect", func() {
+		It("collects from the URL and stores the data under the alias", func() {
+			collector := summary.NewCollector(config)
+			snapshot := collector.Collect(host)
+			Ω(snapshot.Err).Should(BeNil())
+			Ω(snapshot.Data).Should(HaveLen(1))
+			Ω(stripHostPort(snapshot.Data[0].URL)).Should(Equal("http://127.0.0.1:pppp/concourse/teams/main/pipelines/test1"))
+
+			_, ok := collector.Store.Get("local")
+			Ω(ok).Should(BeTrue())
+		})
+	})
+
+	Describe("/api/v1/host/{host}", func() {
+		It("addresses the host by its alias", func() {
+			router := Router(config)
+			defer config.Collector.Stop()
+
+			status := func() int {
+				mockRecorder := httptest.NewRecorder()
+				req, _ := http.NewRequest("GET", "http://example.com/api/v1/host/local", nil)
+				router.ServeHTTP(mockRecorder, req)
+				if mockRecorder.Body.String() == "[]\n" {
+					return 0
+				}
+				return mockRecorder.Code
+			}
+			Eventually(status).Should(Equal(200))
+		})
+	})
+})
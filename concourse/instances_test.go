@@ -0,0 +1,125 @@
+package summary_test
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/gorilla/mux"
+
+	"github.com/FidelityInternational/go-concourse-summary/concourse"
+)
+
+const instancedPipelinesPayload = `[
+  {"id": 1, "name": "app", "instance_vars": {"branch": "main"}, "paused": false, "public": true, "team_name": "main"},
+  {"id": 2, "name": "app", "instance_vars": {"branch": "feature", "pr": 12}, "paused": false, "public": true, "team_name": "main"}
+]`
+
+var _ = Describe("Instanced pipelines", func() {
+	var (
+		instanceServer *httptest.Server
+		config         *summary.Config
+		host           summary.Host
+		jobsVars       []string
+		jobsVarsMutex  sync.Mutex
+	)
+
+	BeforeEach(func() {
+		jobsVars = nil
+		router := mux.NewRouter()
+		router.HandleFunc("/api/v1/teams/main/pipelines", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, instancedPipelinesPayload)
+		})
+		router.HandleFunc("/api/v1/teams/main/pipelines/app/jobs", func(w http.ResponseWriter, r *http.Request) {
+			jobsVarsMutex.Lock()
+			jobsVars = append(jobsVars, r.URL.Query().Get("vars"))
+			jobsVarsMutex.Unlock()
+			if r.URL.Query().Get("vars") == `{"branch":"main"}` {
+				fmt.Fprint(w, jobsPayload)
+				return
+			}
+			fmt.Fprint(w, failedJobPayload)
+		})
+		router.HandleFunc("/api/v1/teams/main/pipelines/app/resources", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "[]")
+		})
+		instanceServer = httptest.NewServer(router)
+
+		config = buildConfig(template.Must(template.ParseGlob("../templates/*")), "main", "http")
+		host = summary.Host{URL: instanceServer.URL, Alias: "instances"}
+	})
+
+	AfterEach(func() {
+		instanceServer.Close()
+	})
+
+	Describe("Collector#Collect", func() {
+		It("collects each instance separately", func() {
+			snapshot := summary.NewCollector(config).Collect(host)
+			Ω(snapshot.Err).Should(BeNil())
+			Ω(jobsVars).Should(ConsistOf(`{"branch":"main"}`, `{"branch":"feature","pr":12}`))
+			Ω(snapshot.Data).Should(HaveLen(2))
+
+			feature := snapshot.Data[0]
+			Ω(feature.InstanceVars).Should(Equal(summary.InstanceVars{"branch": "feature", "pr": float64(12)}))
+			Ω(feature.InstanceVars.Label()).Should(Equal("branch: feature, pr: 12"))
+			Ω(feature.URL).Should(Equal(instanceServer.URL + "/teams/main/pipelines/app?vars=" + url.QueryEscape(`{"branch":"feature","pr":12}`)))
+			Ω(feature.Jobs[0].URL()).Should(Equal(instanceServer.URL + "/teams/main/pipelines/app/jobs/failingJob/builds/3?vars=" + url.QueryEscape(`{"branch":"feature","pr":12}`)))
+
+			main := snapshot.Data[1]
+			Ω(main.InstanceVars).Should(Equal(summary.InstanceVars{"branch": "main"}))
+			Ω(main.Statuses["succeeded"]).Should(Equal(1))
+		})
+	})
+
+	Describe("pages", func() {
+		var router *mux.Router
+
+		request := func(path string) *httptest.ResponseRecorder {
+			mockRecorder := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "http://example.com"+path, nil)
+			router.ServeHTTP(mockRecorder, req)
+			return mockRecorder
+		}
+
+		BeforeEach(func() {
+			config.Hosts = []summary.Host{host}
+			config.CSGroups = summary.CSGroups{{Group: "main-branch", Hosts: []summary.Host{{
+				URL:       instanceServer.URL,
+				Alias:     "instances",
+				Pipelines: []summary.Pipeline{{Name: "app", InstanceVars: summary.InstanceVars{"branch": "main"}}},
+			}}}}
+			router = Router(config)
+			Ω(config.Collector.Collect(host).Err).Should(BeNil())
+		})
+
+		AfterEach(func() {
+			config.Collector.Stop()
+		})
+
+		It("shows the instance vars on tiles", func() {
+			body := stringMinifier(request("/host/instances").Body.String())
+			Ω(body).Should(ContainSubstring(`<spanclass="instance_vars"><span>branch:feature,pr:12</span></span>`))
+			Ω(body).Should(ContainSubstring(`<spanclass="instance_vars"><span>branch:main</span></span>`))
+		})
+
+		It("selects instances in groups by their vars", func() {
+			body := request("/api/v1/group/main-branch").Body.String()
+			Ω(body).Should(ContainSubstring(`"instance_vars":{"branch":"main"}`))
+			Ω(body).ShouldNot(ContainSubstring(`"feature"`))
+		})
+
+		It("shows the jobs of one instance", func() {
+			mockRecorder := request("/host/instances/pipeline/app?vars=" + url.QueryEscape(`{"pr": 12, "branch": "feature"}`))
+			Ω(mockRecorder.Code).Should(Equal(200))
+			Ω(mockRecorder.Body.String()).Should(ContainSubstring("failingJob"))
+			Ω(mockRecorder.Body.String()).ShouldNot(ContainSubstring("testJob1"))
+		})
+	})
+})
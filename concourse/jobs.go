@@ -0,0 +1,150 @@
+package summary
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/concourse/atc"
+	"github.com/gorilla/mux"
+)
+
+// Job is the state of the latest build of a pipeline job
+type Job struct {
+	Team           string
+	Pipeline       string
+	InstanceVars   InstanceVars
+	Name           string
+	PipelineURL    string
+	Status         string
+	LatestBuildNum string
+	Running        bool
+	Paused         bool
+	StartTime      time.Time
+	EndTime        time.Time
+}
+
+type jobsStruct struct {
+	Header headerStruct
+	Jobs   []Job
+}
+
+func newJob(job atc.Job, team string, pipeline pipelineInstance, pipelineURL string) Job {
+	j := Job{
+		Team:         team,
+		Pipeline:     pipeline.Name,
+		InstanceVars: pipeline.InstanceVars,
+		Name:         job.Name,
+		PipelineURL:  pipelineURL,
+		Status:       "pending",
+		Running:      job.NextBuild != nil,
+		Paused:       job.Paused || pipeline.Paused,
+	}
+	if job.FinishedBuild != nil {
+		j.Status = job.FinishedBuild.Status
+		j.LatestBuildNum = job.FinishedBuild.Name
+		if job.FinishedBuild.StartTime != 0 {
+			j.StartTime = time.Unix(job.FinishedBuild.StartTime, 0)
+		}
+		if job.FinishedBuild.EndTime != 0 {
+			j.EndTime = time.Unix(job.FinishedBuild.EndTime, 0)
+		}
+	}
+	return j
+}
+
+// URL returns the concourse page of the latest build of the job, or of the job itself when it
+// has not been built yet
+func (j Job) URL() string {
+	jobURL := fmt.Sprintf("%s/jobs/%s", j.PipelineURL, url.PathEscape(j.Name))
+	if j.LatestBuildNum != "" {
+		jobURL = fmt.Sprintf("%s/builds/%s", jobURL, url.PathEscape(j.LatestBuildNum))
+	}
+	return withQuery(jobURL, j.InstanceVars.query())
+}
+
+// StartTimeAgoDays returns how many whole days ago the latest build started
+func (j Job) StartTimeAgoDays() int {
+	if j.StartTime.IsZero() {
+		return 0
+	}
+	return int(time.Since(j.StartTime).Hours() / 24)
+}
+
+// RunTime returns how long the latest build ran for
+func (j Job) RunTime() string {
+	if j.StartTime.IsZero() || j.EndTime.IsZero() {
+		return "-"
+	}
+	return j.EndTime.Sub(j.StartTime).String()
+}
+
+// pipelineJobs returns the jobs of a pipeline, limited to those of team, of the instance with
+// instanceVars and in group unless they are blank
+func pipelineJobs(data []Data, team, pipeline, instanceVars, group string) ([]Job, bool) {
+	var (
+		jobs  []Job
+		found bool
+		seen  = map[string]bool{}
+	)
+	for _, datum := range data {
+		if datum.Pipeline != pipeline || (team != "" && datum.Team != team) || (instanceVars != "" && datum.InstanceVars.String() != instanceVars) || (group != "" && datum.Group != group) {
+			continue
+		}
+		found = true
+		for _, job := range datum.Jobs {
+			key := datum.Team + "/" + datum.InstanceVars.String() + "/" + job.Name
+			if !seen[key] {
+				seen[key] = true
+				jobs = append(jobs, job)
+			}
+		}
+	}
+	return jobs, found
+}
+
+// PipelineSummary renders and serves a page with a tile for each job of a pipeline, optionally
+// limited to a single team, instance and group of the pipeline
+func (config *Config) PipelineSummary(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	host, ok := config.lookupHost(vars["host"])
+	if !ok {
+		config.hostNotFound(w, vars["host"])
+		return
+	}
+	pipeline := vars["pipeline"]
+	group := r.URL.Query().Get("group")
+	team := r.URL.Query().Get("team")
+	instanceVars, err := normalInstanceVars(r.URL.Query().Get("vars"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "Invalid vars %s", err.Error())
+		return
+	}
+	config.Collector.Watch(host)
+	snapshot, collected := config.Collector.Store.Get(host.Name())
+	if snapshot.Err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "Error collecting data from concourse (%s) please refer to logs for more details", host.Name())
+		return
+	}
+
+	jobs, found := pipelineJobs(snapshot.Data, team, pipeline, instanceVars, group)
+	if collected && !found {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, "Pipeline %s was not found on concourse (%s)", pipeline, host.Name())
+		return
+	}
+
+	err = config.Templates.ExecuteTemplate(w, "jobs", jobsStruct{
+		Header: headerStruct{
+			RefreshInterval: config.RefreshInterval,
+			UpdatedAt:       snapshot.UpdatedAt,
+		},
+		Jobs: jobs,
+	})
+	if err != nil {
+		panic(err.Error())
+	}
+}
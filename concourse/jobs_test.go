@@ -0,0 +1,84 @@
+package summary_test
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/FidelityInternational/go-concourse-summary/concourse"
+)
+
+var _ = Describe("#PipelineSummary", func() {
+	var (
+		templates    = template.Must(template.ParseGlob("../templates/*"))
+		mockRecorder *httptest.ResponseRecorder
+		config       *summary.Config
+		path         string
+	)
+
+	BeforeEach(func() {
+		mocks := []MockRoute{
+			{"GET", "/api/v1/teams/main/pipelines", examplePipeline, 200, "", nil},
+			{"GET", "/api/v1/teams/main/pipelines/cf-example-pipeline/jobs", examplePipelineJobs, 200, "", nil},
+			{"GET", "/api/v1/teams/main/pipelines/cf-example-pipeline/resources", "[]", 200, "", nil},
+		}
+		setupMultiple(mocks)
+		config = buildConfig(templates, "main", "http")
+	})
+
+	AfterEach(func() {
+		config.Collector.Stop()
+		teardown()
+	})
+
+	JustBeforeEach(func() {
+		router := Router(config)
+		config.Collector.Collect(summary.Host{FQDN: Host(server)})
+		mockRecorder = httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", fmt.Sprintf("http://example.com/host/%s%s", Host(server), path), nil)
+		router.ServeHTTP(mockRecorder, req)
+	})
+
+	Context("when the pipeline exists", func() {
+		BeforeEach(func() {
+			path = "/pipeline/cf-example-pipeline?group=test-group"
+		})
+
+		It("renders a tile for each job", func() {
+			Ω(mockRecorder.Code).Should(Equal(200))
+			body := stripHostPort(stringMinifier(mockRecorder.Body.String()))
+			Ω(body).Should(ContainSubstring(stringMinifier(`
+  <a href="http://127.0.0.1:pppp/teams/main/pipelines/cf-example-pipeline/jobs/testJob1/builds/4" target="_blank" class="outer">
+  <div class="status">
+    <div class="succeeded" style="width: 100%;"></div>
+  </div>
+  `)))
+			Ω(body).Should(MatchRegexp(`<span><span>cf-example-pipeline</span></span><span><span>testJob1</span></span><span><span>#4\(\d+d\)28s</span></span>`))
+		})
+	})
+
+	Context("when the group does not exist", func() {
+		BeforeEach(func() {
+			path = "/pipeline/cf-example-pipeline?group=missing"
+		})
+
+		It("returns a not found error", func() {
+			Ω(mockRecorder.Code).Should(Equal(404))
+			Ω(mockRecorder.Body.String()).Should(MatchRegexp(`Pipeline cf-example-pipeline was not found on concourse \(127.0.0.1:\d{1,6}\)`))
+		})
+	})
+
+	Context("when the pipeline does not exist", func() {
+		BeforeEach(func() {
+			path = "/pipeline/missing"
+		})
+
+		It("returns a not found error", func() {
+			Ω(mockRecorder.Code).Should(Equal(404))
+		})
+	})
+})
@@ -0,0 +1,214 @@
+package summary
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var metricStatuses = []string{"succeeded", "failed", "errored", "aborted", "pending"}
+
+// Metrics records statistics about the collection of data from concourse hosts
+type Metrics struct {
+	sync.Mutex
+	hosts map[string]*hostMetrics
+}
+
+type hostMetrics struct {
+	collections        int
+	collectionErrors   int
+	collectionSeconds  float64
+	requests           int
+	requestErrors      int
+	requestSeconds     float64
+	lastCollectionTime time.Time
+}
+
+// NewMetrics - creates an empty set of collection metrics
+func NewMetrics() *Metrics {
+	return &Metrics{hosts: map[string]*hostMetrics{}}
+}
+
+func (m *Metrics) host(host string) *hostMetrics {
+	metrics, ok := m.hosts[host]
+	if !ok {
+		metrics = &hostMetrics{}
+		m.hosts[host] = metrics
+	}
+	return metrics
+}
+
+// forget drops the metrics of a host
+func (m *Metrics) forget(host string) {
+	if m == nil {
+		return
+	}
+	m.Lock()
+	defer m.Unlock()
+	delete(m.hosts, host)
+}
+
+func (m *Metrics) collected(host string, duration time.Duration, err error) {
+	if m == nil {
+		return
+	}
+	m.Lock()
+	defer m.Unlock()
+	metrics := m.host(host)
+	metrics.collections++
+	metrics.collectionSeconds = duration.Seconds()
+	metrics.lastCollectionTime = time.Now()
+	if err != nil {
+		metrics.collectionErrors++
+	}
+}
+
+func (m *Metrics) requested(host string, duration time.Duration, failed bool) {
+	if m == nil {
+		return
+	}
+	m.Lock()
+	defer m.Unlock()
+	metrics := m.host(host)
+	metrics.requests++
+	metrics.requestSeconds += duration.Seconds()
+	if failed {
+		metrics.requestErrors++
+	}
+}
+
+// metricsTransport records the latency and outcome of every request made to a concourse host
+type metricsTransport struct {
+	base    http.RoundTripper
+	host    string
+	metrics *Metrics
+}
+
+func (t *metricsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	t.metrics.requested(t.host, time.Since(start), err != nil || resp.StatusCode >= 400)
+	return resp, err
+}
+
+// Metrics serves the latest pipeline statuses and collection statistics in the prometheus text format
+func (config *Config) Metrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	writeMetrics(w, config.Collector)
+}
+
+type metric struct {
+	name, help, kind string
+	samples          []sample
+}
+
+type sample struct {
+	labels [][2]string
+	value  float64
+}
+
+func (m *metric) add(value float64, labels ...string) {
+	var pairs [][2]string
+	for i := 0; i+1 < len(labels); i += 2 {
+		pairs = append(pairs, [2]string{labels[i], labels[i+1]})
+	}
+	m.samples = append(m.samples, sample{labels: pairs, value: value})
+}
+
+func writeMetrics(w io.Writer, collector *Collector) {
+	jobs := &metric{name: "concourse_summary_jobs", kind: "gauge", help: "Number of jobs in a pipeline group by the status of their latest finished build"}
+	running := &metric{name: "concourse_summary_running", kind: "gauge", help: "Whether any job in a pipeline group has a build running"}
+	paused := &metric{name: "concourse_summary_paused", kind: "gauge", help: "Whether a pipeline is paused"}
+	up := &metric{name: "concourse_summary_up", kind: "gauge", help: "Whether the latest collection from a host succeeded"}
+
+	snapshots := collector.Store.All()
+	for _, host := range sortedKeys(snapshots) {
+		snapshot := snapshots[host]
+		up.add(boolValue(snapshot.Err == nil), "host", host)
+		for _, datum := range snapshot.Data {
+			for _, status := range datumStatuses(datum) {
+				jobs.add(float64(datum.Statuses[status]), "host", host, "team", datum.Team, "pipeline", datum.Pipeline, "instance_vars", datum.InstanceVars.String(), "group", datum.Group, "status", status)
+			}
+			running.add(boolValue(datum.Running), "host", host, "team", datum.Team, "pipeline", datum.Pipeline, "instance_vars", datum.InstanceVars.String(), "group", datum.Group)
+			paused.add(boolValue(datum.Paused), "host", host, "team", datum.Team, "pipeline", datum.Pipeline, "instance_vars", datum.InstanceVars.String(), "group", datum.Group)
+		}
+	}
+
+	collections := &metric{name: "concourse_summary_collections_total", kind: "counter", help: "Number of collections from a host"}
+	collectionErrors := &metric{name: "concourse_summary_collection_errors_total", kind: "counter", help: "Number of collections from a host that failed"}
+	collectionSeconds := &metric{name: "concourse_summary_collection_duration_seconds", kind: "gauge", help: "Time taken by the latest collection from a host"}
+	lastCollection := &metric{name: "concourse_summary_last_collection_timestamp_seconds", kind: "gauge", help: "Unix time of the latest collection from a host"}
+	requests := &metric{name: "concourse_summary_api_requests_total", kind: "counter", help: "Number of requests to the concourse API of a host"}
+	requestSeconds := &metric{name: "concourse_summary_api_request_seconds_total", kind: "counter", help: "Total time spent on requests to the concourse API of a host"}
+	requestErrors := &metric{name: "concourse_summary_api_request_errors_total", kind: "counter", help: "Number of requests to the concourse API of a host that failed"}
+
+	collector.Metrics.Lock()
+	hosts := make([]string, 0, len(collector.Metrics.hosts))
+	for host := range collector.Metrics.hosts {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	for _, host := range hosts {
+		metrics := collector.Metrics.hosts[host]
+		collections.add(float64(metrics.collections), "host", host)
+		collectionErrors.add(float64(metrics.collectionErrors), "host", host)
+		collectionSeconds.add(metrics.collectionSeconds, "host", host)
+		lastCollection.add(float64(metrics.lastCollectionTime.Unix()), "host", host)
+		requests.add(float64(metrics.requests), "host", host)
+		requestSeconds.add(metrics.requestSeconds, "host", host)
+		requestErrors.add(float64(metrics.requestErrors), "host", host)
+	}
+	collector.Metrics.Unlock()
+
+	for _, m := range []*metric{jobs, running, paused, up, collections, collectionErrors, collectionSeconds, lastCollection, requests, requestSeconds, requestErrors} {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.kind)
+		for _, s := range m.samples {
+			var labels []string
+			for _, label := range s.labels {
+				labels = append(labels, fmt.Sprintf(`%s="%s"`, label[0], escapeLabel(label[1])))
+			}
+			fmt.Fprintf(w, "%s{%s} %v\n", m.name, strings.Join(labels, ","), s.value)
+		}
+	}
+}
+
+// datumStatuses returns the standard build statuses followed by any others present in the data
+func datumStatuses(datum Data) []string {
+	statuses := append([]string{}, metricStatuses...)
+	known := map[string]bool{}
+	for _, status := range metricStatuses {
+		known[status] = true
+	}
+	var others []string
+	for status := range datum.Statuses {
+		if !known[status] {
+			others = append(others, status)
+		}
+	}
+	sort.Strings(others)
+	return append(statuses, others...)
+}
+
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+func boolValue(value bool) float64 {
+	if value {
+		return 1
+	}
+	return 0
+}
+
+func sortedKeys(snapshots map[string]Snapshot) []string {
+	keys := make([]string, 0, len(snapshots))
+	for key := range snapshots {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,60 @@
+package summary_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/FidelityInternational/go-concourse-summary/concourse"
+)
+
+var _ = Describe("config#Metrics", func() {
+	var (
+		mockRecorder *httptest.ResponseRecorder
+		config       *summary.Config
+	)
+
+	BeforeEach(func() {
+		mocks := []MockRoute{
+			{"GET", "/api/v1/teams/main/pipelines", pipelinesPayload, 200, "", nil},
+			{"GET", "/api/v1/teams/main/pipelines/test1/jobs", jobsPayload, 200, "", nil},
+			{"GET", "/api/v1/teams/main/pipelines/test1/resources", "[]", 200, "", nil},
+		}
+		setupMultiple(mocks)
+
+		config = buildConfig(nil, "main", "http")
+		router := Router(config)
+		config.Collector.Collect(summary.Host{FQDN: Host(server)})
+		mockRecorder = httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "http://example.com/metrics", nil)
+		router.ServeHTTP(mockRecorder, req)
+	})
+
+	AfterEach(func() {
+		config.Collector.Stop()
+		teardown()
+	})
+
+	It("exports pipeline statuses as gauges", func() {
+		Ω(mockRecorder.Code).Should(Equal(200))
+		body := stripHostPort(mockRecorder.Body.String())
+		Ω(body).Should(ContainSubstring("# TYPE concourse_summary_jobs gauge\n"))
+		for _, status := range []string{"succeeded", "failed", "errored", "aborted", "pending", "started"} {
+			Ω(body).Should(ContainSubstring(`concourse_summary_jobs{host="127.0.0.1:pppp",team="main",pipeline="test1",instance_vars="",group="",status="` + status + `"} 1` + "\n"))
+		}
+		Ω(body).Should(ContainSubstring(`concourse_summary_running{host="127.0.0.1:pppp",team="main",pipeline="test1",instance_vars="",group=""} 0` + "\n"))
+		Ω(body).Should(ContainSubstring(`concourse_summary_paused{host="127.0.0.1:pppp",team="main",pipeline="test1",instance_vars="",group=""} 0` + "\n"))
+		Ω(body).Should(ContainSubstring(`concourse_summary_up{host="127.0.0.1:pppp"} 1` + "\n"))
+	})
+
+	It("exports collector metrics for each host", func() {
+		body := stripHostPort(mockRecorder.Body.String())
+		Ω(body).Should(ContainSubstring(`concourse_summary_collections_total{host="127.0.0.1:pppp"} 1` + "\n"))
+		Ω(body).Should(ContainSubstring(`concourse_summary_collection_errors_total{host="127.0.0.1:pppp"} 0` + "\n"))
+		Ω(body).Should(ContainSubstring(`concourse_summary_api_requests_total{host="127.0.0.1:pppp"} 4` + "\n"))
+		Ω(body).Should(ContainSubstring(`concourse_summary_api_request_errors_total{host="127.0.0.1:pppp"} 0` + "\n"))
+		Ω(body).Should(MatchRegexp(`concourse_summary_api_request_seconds_total\{host="127.0.0.1:pppp"\} [0-9.e-]+\n`))
+	})
+})
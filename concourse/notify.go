@@ -0,0 +1,319 @@
+package summary
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+var notificationTimeout = 10 * time.Second
+
+const (
+	// transitionFailed is sent when a pipeline group that was passing has failed or errored jobs
+	transitionFailed = "failed"
+	// transitionFixed is sent when a pipeline group that was failing no longer has failed or
+	// errored jobs
+	transitionFixed = "fixed"
+	// transitionBrokenResource is sent when a resource of a pipeline group starts failing to check
+	transitionBrokenResource = "broken_resource"
+
+	notificationWebhook = "webhook"
+	notificationSlack   = "slack"
+)
+
+// Notification is a webhook that is sent the transitions of pipeline groups, either as JSON or
+// as a Slack message. Payload is an optional text/template for the body of the request.
+type Notification struct {
+	URL     string `json:"url"`
+	Type    string `json:"type"`
+	Payload string `json:"payload"`
+}
+
+// Transition is a change in the state of a pipeline group between two collections
+type Transition struct {
+	Transition      string           `json:"transition"`
+	Team            string           `json:"team"`
+	Pipeline        string           `json:"pipeline"`
+	InstanceVars    InstanceVars     `json:"instance_vars,omitempty"`
+	Group           string           `json:"group"`
+	URL             string           `json:"pipeline_url"`
+	Statuses        map[string]int   `json:"statuses"`
+	BrokenResources []BrokenResource `json:"broken_resources,omitempty"`
+
+	key string
+}
+
+// notificationMessage is the value that notification payloads are rendered from
+type notificationMessage struct {
+	Host        string       `json:"host"`
+	Group       string       `json:"group,omitempty"`
+	Transitions []Transition `json:"transitions"`
+}
+
+// failing reports whether a pipeline group has failed or errored jobs
+func failing(d Data) bool {
+	return d.Statuses["failed"]+d.Statuses["errored"] > 0
+}
+
+func newTransition(transition string, d Data) Transition {
+	return Transition{
+		Transition:   transition,
+		Team:         d.Team,
+		Pipeline:     d.Pipeline,
+		InstanceVars: d.InstanceVars,
+		Group:        d.Group,
+		URL:          d.URL,
+		Statuses:     d.Statuses,
+		key:          d.Key(),
+	}
+}
+
+// transitions compares the data of two successive collections from a host. Pipeline groups that
+// were not in the previous collection have nothing to compare with and are left out.
+func transitions(previous, current []Data) []Transition {
+	before := map[string]Data{}
+	for _, datum := range previous {
+		before[datum.Key()] = datum
+	}
+
+	var changes []Transition
+	for _, datum := range current {
+		last, ok := before[datum.Key()]
+		if !ok {
+			continue
+		}
+		switch {
+		case failing(datum) && !failing(last):
+			changes = append(changes, newTransition(transitionFailed, datum))
+		case !failing(datum) && failing(last):
+			changes = append(changes, newTransition(transitionFixed, datum))
+		}
+
+		var broken []BrokenResource
+		for _, resource := range datum.BrokenResources {
+			if !hasBrokenResource(last.BrokenResources, resource.Name) {
+				broken = append(broken, resource)
+			}
+		}
+		if broken != nil {
+			transition := newTransition(transitionBrokenResource, datum)
+			transition.BrokenResources = broken
+			changes = append(changes, transition)
+		}
+	}
+	return changes
+}
+
+func hasBrokenResource(resources []BrokenResource, name string) bool {
+	for _, resource := range resources {
+		if resource.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// notifier sends the transitions seen between collections to the notifications of the config
+// and of the groups that show them
+type notifier struct {
+	sync.Mutex
+	client *http.Client
+	// data is the last data successfully collected from each host, so that a failed collection
+	// in between does not hide or repeat a transition
+	data map[string][]Data
+}
+
+func newNotifier() *notifier {
+	return &notifier{
+		client: &http.Client{Timeout: notificationTimeout},
+		data:   map[string][]Data{},
+	}
+}
+
+// forget drops the last data collected from a host
+func (n *notifier) forget(host string) {
+	n.Lock()
+	defer n.Unlock()
+	delete(n.data, host)
+}
+
+// collected compares a new snapshot of a host with the last successful one and sends any
+// transitions. Each transition is sent to a URL at most once, even when several groups that
+// show the pipeline group share a URL.
+func (n *notifier) collected(host Host, snapshot Snapshot, config *Config) {
+	if snapshot.Err != nil {
+		return
+	}
+	n.Lock()
+	previous, ok := n.data[host.Name()]
+	n.data[host.Name()] = snapshot.Data
+	n.Unlock()
+	if !ok {
+		return
+	}
+	changes := transitions(previous, snapshot.Data)
+	if len(changes) == 0 {
+		return
+	}
+
+	sent := map[string]bool{}
+	n.send(config.Notifications, notificationMessage{Host: host.Name(), Transitions: changes}, sent)
+	for _, csGroup := range config.CSGroups {
+		if len(csGroup.Notifications) == 0 {
+			continue
+		}
+		var shown []Transition
+		for _, transition := range changes {
+			if csGroup.shows(host.Name(), transition, config) {
+				shown = append(shown, transition)
+			}
+		}
+		if shown != nil {
+			n.send(csGroup.Notifications, notificationMessage{Host: host.Name(), Group: csGroup.Group, Transitions: shown}, sent)
+		}
+	}
+}
+
+// shows reports whether a transition of a pipeline group on a host is shown on the group page
+func (csGroup CSGroup) shows(hostName string, transition Transition, config *Config) bool {
+	datum := Data{Team: transition.Team, Pipeline: transition.Pipeline, InstanceVars: transition.InstanceVars, Group: transition.Group}
+	for _, host := range csGroup.Hosts {
+		if host.Name() == hostName && len(filterData(filterTeams([]Data{datum}, host.teams(config)), host.Pipelines, config.patterns)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// send posts the transitions of message that are not yet in sent to each notification
+func (n *notifier) send(notifications []Notification, message notificationMessage, sent map[string]bool) {
+	for _, notification := range notifications {
+		unsent := message
+		unsent.Transitions = nil
+		for _, transition := range message.Transitions {
+			key := notification.URL + " " + transition.key + " " + transition.Transition
+			if !sent[key] {
+				sent[key] = true
+				unsent.Transitions = append(unsent.Transitions, transition)
+			}
+		}
+		if unsent.Transitions == nil {
+			continue
+		}
+		go func(notification Notification, message notificationMessage) {
+			if err := n.post(notification, message); err != nil {
+				fmt.Printf("Error sending notification for concourse (%s) to %s: %s\n", message.Host, redactURL(notification.URL), err.Error())
+			}
+		}(notification, unsent)
+	}
+}
+
+func (n *notifier) post(notification Notification, message notificationMessage) error {
+	body, err := notification.render(message)
+	if err != nil {
+		return err
+	}
+	resp, err := n.client.Post(notification.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response %s", resp.Status)
+	}
+	return nil
+}
+
+// render returns the body of the request for a message, from the payload template when it is
+// set or otherwise as JSON for webhooks and as a Slack message for Slack
+func (notification Notification) render(message notificationMessage) ([]byte, error) {
+	if notification.Payload != "" {
+		payload, err := notification.template()
+		if err != nil {
+			return nil, err
+		}
+		var body bytes.Buffer
+		err = payload.Execute(&body, message)
+		return body.Bytes(), err
+	}
+	if notification.Type == notificationSlack {
+		return json.Marshal(struct {
+			Text string `json:"text"`
+		}{slackText(message)})
+	}
+	return json.Marshal(message)
+}
+
+func (notification Notification) template() (*template.Template, error) {
+	return template.New("payload").Funcs(template.FuncMap{
+		"json": func(value interface{}) (string, error) {
+			encoded, err := json.Marshal(value)
+			return string(encoded), err
+		},
+	}).Parse(notification.Payload)
+}
+
+func slackText(message notificationMessage) string {
+	var lines []string
+	for _, transition := range message.Transitions {
+		name := transition.Pipeline
+		if label := transition.InstanceVars.Label(); label != "" {
+			name += " (" + label + ")"
+		}
+		if transition.Group != "" {
+			name += "/" + transition.Group
+		}
+		link := fmt.Sprintf("<%s|%s/%s> on %s", transition.URL, transition.Team, name, message.Host)
+		switch transition.Transition {
+		case transitionFailed:
+			lines = append(lines, fmt.Sprintf(":red_circle: %s is failing", link))
+		case transitionFixed:
+			lines = append(lines, fmt.Sprintf(":large_green_circle: %s is fixed", link))
+		case transitionBrokenResource:
+			for _, resource := range transition.BrokenResources {
+				lines = append(lines, fmt.Sprintf(":warning: %s has a broken resource %s: %s", link, resource.Name, resource.CheckError))
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// redactURL leaves the path out of a URL for logging, as webhook URLs often contain a secret
+func redactURL(value string) string {
+	u, err := url.Parse(value)
+	if err != nil {
+		return "webhook"
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+func (notification Notification) validate(key string) error {
+	u, err := url.Parse(notification.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ConfigError{Key: key + ".url", Message: "must be an absolute http or https URL"}
+	}
+	switch notification.Type {
+	case "", notificationWebhook, notificationSlack:
+	default:
+		return ConfigError{Key: key + ".type", Message: fmt.Sprintf("%s is not webhook or slack", notification.Type)}
+	}
+	if _, err := notification.template(); err != nil {
+		return ConfigError{Key: key + ".payload", Message: err.Error()}
+	}
+	return nil
+}
+
+func validateNotifications(key string, notifications []Notification) error {
+	for i, notification := range notifications {
+		if err := notification.validate(fmt.Sprintf("%s[%d]", key, i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
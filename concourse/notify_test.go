@@ -0,0 +1,179 @@
+package summary_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/FidelityInternational/go-concourse-summary/concourse"
+)
+
+// buildPayload returns the jobs of a pipeline with a single job whose latest build has status
+func buildPayload(status string) string {
+	return fmt.Sprintf(`[{
+  "id": 1,
+  "name": "build",
+  "team_name": "main",
+  "inputs": [{"name": "repo", "resource": "repo"}],
+  "finished_build": {"id": 1, "name": "1", "status": "%s"}
+}]`, status)
+}
+
+var _ = Describe("Notifications", func() {
+	var (
+		concourse *fakeConcourse
+		receiver  *httptest.Server
+		config    *summary.Config
+		host      summary.Host
+		mutex     sync.Mutex
+		received  map[string][]string
+	)
+
+	set := func(jobs, resources string) {
+		concourse.mock(jobsRoute("pipeline-a", jobs), resourcesRoute("pipeline-a", resources))
+	}
+
+	bodies := func(path string) func() []string {
+		return func() []string {
+			mutex.Lock()
+			defer mutex.Unlock()
+			return append([]string{}, received[path]...)
+		}
+	}
+
+	BeforeEach(func() {
+		received = map[string][]string{}
+		concourse = newFakeConcourse(pipelinesRoute(multiplePipelinesPayload))
+		concourse.mock(pipelineRoutes(buildPayload("succeeded"), "pipeline-a", "pipeline-b", "pipeline-c")...)
+
+		receiver = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			mutex.Lock()
+			defer mutex.Unlock()
+			received[r.URL.Path] = append(received[r.URL.Path], string(body))
+		}))
+
+		config = buildConfig(nil, "main", "http")
+		host = concourse.host("ci")
+		config.Notifications = []summary.Notification{{URL: receiver.URL + "/all"}}
+		config.CSGroups = summary.CSGroups{
+			{
+				Group: "a",
+				Hosts: []summary.Host{{URL: concourse.URL, Alias: "ci", Pipelines: []summary.Pipeline{{Name: "pipeline-a"}}}},
+				Notifications: []summary.Notification{
+					{URL: receiver.URL + "/slack", Type: "slack"},
+					{URL: receiver.URL + "/all"},
+				},
+			},
+			{
+				Group:         "c",
+				Hosts:         []summary.Host{{URL: concourse.URL, Alias: "ci", Pipelines: []summary.Pipeline{{Name: "pipeline-c"}}}},
+				Notifications: []summary.Notification{{URL: receiver.URL + "/c", Payload: `{{range .Transitions}}{{.Pipeline}} {{.Transition}}{{end}}`}},
+			},
+		}
+		collector := summary.NewCollector(config)
+		config.Collector = collector
+		Ω(collector.Collect(host).Err).Should(BeNil())
+	})
+
+	AfterEach(func() {
+		concourse.Close()
+		receiver.Close()
+	})
+
+	Context("when nothing changes", func() {
+		It("sends nothing", func() {
+			Ω(config.Collector.Collect(host).Err).Should(BeNil())
+			Consistently(bodies("/all"), 200*time.Millisecond).Should(BeEmpty())
+		})
+	})
+
+	Context("when a pipeline starts failing", func() {
+		BeforeEach(func() {
+			set(buildPayload("failed"), "[]")
+			Ω(config.Collector.Collect(host).Err).Should(BeNil())
+		})
+
+		It("sends the transition once to each URL", func() {
+			Eventually(bodies("/all")).Should(HaveLen(1))
+			var message struct {
+				Host        string               `json:"host"`
+				Transitions []summary.Transition `json:"transitions"`
+			}
+			Ω(json.Unmarshal([]byte(bodies("/all")()[0]), &message)).Should(Succeed())
+			Ω(message.Host).Should(Equal("ci"))
+			Ω(message.Transitions).Should(HaveLen(1))
+			Ω(message.Transitions[0].Transition).Should(Equal("failed"))
+			Ω(message.Transitions[0].Pipeline).Should(Equal("pipeline-a"))
+			Consistently(bodies("/all"), 200*time.Millisecond).Should(HaveLen(1))
+		})
+
+		It("sends a Slack message to the group showing the pipeline", func() {
+			Eventually(bodies("/slack")).Should(HaveLen(1))
+			Ω(bodies("/slack")()[0]).Should(MatchJSON(fmt.Sprintf(`{"text": ":red_circle: <%s/teams/main/pipelines/pipeline-a|main/pipeline-a> on ci is failing"}`, concourse.URL)))
+		})
+
+		It("sends nothing to groups not showing the pipeline", func() {
+			Consistently(bodies("/c"), 200*time.Millisecond).Should(BeEmpty())
+		})
+
+		Context("and is fixed", func() {
+			BeforeEach(func() {
+				Eventually(bodies("/slack")).Should(HaveLen(1))
+				set(buildPayload("succeeded"), "[]")
+				Ω(config.Collector.Collect(host).Err).Should(BeNil())
+			})
+
+			It("sends the fix", func() {
+				Eventually(bodies("/slack")).Should(HaveLen(2))
+				Ω(bodies("/slack")()[1]).Should(ContainSubstring(":large_green_circle:"))
+			})
+		})
+
+		Context("and the host cannot be collected in between", func() {
+			BeforeEach(func() {
+				Eventually(bodies("/slack")).Should(HaveLen(1))
+				set("{", "[]")
+				Ω(config.Collector.Collect(host).Err).ShouldNot(BeNil())
+				set(buildPayload("failed"), "[]")
+				Ω(config.Collector.Collect(host).Err).Should(BeNil())
+			})
+
+			It("does not send the transition again", func() {
+				Consistently(bodies("/slack"), 200*time.Millisecond).Should(HaveLen(1))
+			})
+		})
+	})
+
+	Context("when a resource starts failing to check", func() {
+		BeforeEach(func() {
+			set(buildPayload("succeeded"), `[{"name": "repo", "type": "git", "failing_to_check": true, "check_error": "authentication required"}]`)
+			Ω(config.Collector.Collect(host).Err).Should(BeNil())
+		})
+
+		It("sends the broken resource", func() {
+			Eventually(bodies("/slack")).Should(HaveLen(1))
+			Ω(bodies("/slack")()[0]).Should(ContainSubstring(":warning:"))
+			Ω(bodies("/slack")()[0]).Should(ContainSubstring("has a broken resource repo: authentication required"))
+		})
+	})
+
+	Context("when the group has a payload template", func() {
+		BeforeEach(func() {
+			config.CSGroups[1].Hosts[0].Pipelines = []summary.Pipeline{{Name: "pipeline-*"}}
+			set(buildPayload("failed"), "[]")
+			Ω(config.Collector.Collect(host).Err).Should(BeNil())
+		})
+
+		It("renders the payload", func() {
+			Eventually(bodies("/c")).Should(Equal([]string{"pipeline-a failed"}))
+		})
+	})
+})
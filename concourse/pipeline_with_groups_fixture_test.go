@@ -89,3 +89,27 @@ const examplePipelineJobs = `[
         ]
     }
 ]`
+
+const examplePipelineBrokenResources = `[
+    {
+        "name": "testResource1",
+        "pipeline_name": "cf-example-pipeline",
+        "team_name": "main",
+        "type": "git",
+        "failing_to_check": true,
+        "check_error": "git clone failed: authentication required"
+    },
+    {
+        "name": "testResource2",
+        "pipeline_name": "cf-example-pipeline",
+        "team_name": "main",
+        "type": "git"
+    },
+    {
+        "name": "unusedResource",
+        "pipeline_name": "cf-example-pipeline",
+        "team_name": "main",
+        "type": "registry-image",
+        "failing_to_check": true
+    }
+]`
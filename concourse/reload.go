@@ -0,0 +1,75 @@
+package summary
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+var reloadDelay = 100 * time.Millisecond
+
+// WatchConfigFile reloads the server config with load whenever the config file at path changes
+// or the process receives SIGHUP. If the new config fails to load the current config is kept.
+// The returned function stops watching.
+func (s *Server) WatchConfigFile(path string, load func() (*Config, error)) (func(), error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	// the directory is watched rather than the file so that files replaced by editors, or by
+	// symlink swaps as with kubernetes config maps, are still seen
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+
+	done := make(chan struct{})
+	contents, _ := ioutil.ReadFile(path)
+	go func() {
+		defer watcher.Close()
+		defer signal.Stop(hangups)
+		var changed <-chan time.Time
+		for {
+			select {
+			case <-done:
+				return
+			case <-watcher.Events:
+				// editors often write a file in several steps, so wait for them to finish
+				changed = time.After(reloadDelay)
+			case err := <-watcher.Errors:
+				fmt.Printf("Error watching config file (%s): %s\n", path, err.Error())
+			case <-changed:
+				latest, err := ioutil.ReadFile(path)
+				if err != nil || bytes.Equal(latest, contents) {
+					continue
+				}
+				contents = latest
+				s.reload(path, load)
+			case <-hangups:
+				contents, _ = ioutil.ReadFile(path)
+				s.reload(path, load)
+			}
+		}
+	}()
+	return func() { close(done) }, nil
+}
+
+func (s *Server) reload(path string, load func() (*Config, error)) {
+	config, err := load()
+	if err != nil {
+		fmt.Printf("Error reloading config file (%s), keeping the current config: %s\n", path, err.Error())
+		return
+	}
+	s.Reload(config)
+	fmt.Printf("Reloaded config file (%s)\n", path)
+}
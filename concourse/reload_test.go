@@ -0,0 +1,147 @@
+package summary_test
+
+import (
+	"html/template"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"syscall"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/gorilla/mux"
+
+	"github.com/FidelityInternational/go-concourse-summary/concourse"
+)
+
+var _ = Describe("Server", func() {
+	var (
+		templates = template.Must(template.ParseGlob("../templates/*"))
+		config    *summary.Config
+		srv       *summary.Server
+		router    *mux.Router
+	)
+
+	index := func() string {
+		mockRecorder := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "http://example.com/", nil)
+		router.ServeHTTP(mockRecorder, req)
+		return mockRecorder.Body.String()
+	}
+
+	BeforeEach(func() {
+		mocks := []MockRoute{
+			{"GET", "/api/v1/teams/main/pipelines", "[]", 200, "", nil},
+		}
+		setupMultiple(mocks)
+
+		config = buildConfig(templates, "main", "http")
+		config.CSGroups = summary.CSGroups{{Group: "before", Hosts: []summary.Host{{FQDN: "127.0.0.1:1"}}}}
+		srv = summary.CreateServer(config)
+		router = srv.Start()
+	})
+
+	AfterEach(func() {
+		config.Collector.Stop()
+		teardown()
+	})
+
+	Describe("#Reload", func() {
+		It("serves requests with the new config", func() {
+			Ω(index()).Should(ContainSubstring(`/group/before`))
+			Eventually(func() bool {
+				_, ok := config.Collector.Store.Get("127.0.0.1:1")
+				return ok
+			}).Should(BeTrue())
+
+			newConfig := buildConfig(nil, "main", "http")
+			newConfig.CSGroups = summary.CSGroups{{Group: "after", Hosts: []summary.Host{{FQDN: Host(server)}}}}
+			srv.Reload(newConfig)
+
+			Ω(index()).Should(ContainSubstring(`/group/after`))
+			Ω(index()).ShouldNot(ContainSubstring(`/group/before`))
+			Ω(newConfig.Templates).Should(Equal(templates))
+			Ω(newConfig.Collector).Should(Equal(config.Collector))
+
+			_, ok := config.Collector.Store.Get("127.0.0.1:1")
+			Ω(ok).Should(BeFalse())
+			Eventually(func() bool {
+				_, ok := config.Collector.Store.Get(Host(server))
+				return ok
+			}).Should(BeTrue())
+		})
+
+		It("polls at the new refresh interval", func() {
+			newConfig := buildConfig(nil, "main", "http")
+			newConfig.RefreshInterval = 3600
+			newConfig.Hosts = []summary.Host{{FQDN: Host(server)}}
+			srv.Reload(newConfig)
+			Eventually(func() bool {
+				_, ok := config.Collector.Store.Get(Host(server))
+				return ok
+			}).Should(BeTrue())
+
+			updates, unsubscribe := config.Collector.Subscribe()
+			defer unsubscribe()
+			faster := buildConfig(nil, "main", "http")
+			faster.RefreshInterval = 1
+			faster.Hosts = newConfig.Hosts
+			srv.Reload(faster)
+
+			Eventually(updates, 3).Should(Receive())
+			Eventually(updates, 3).Should(Receive())
+		})
+	})
+
+	Describe("#WatchConfigFile", func() {
+		var (
+			dir, path string
+			loads     int32
+			stop      func()
+		)
+
+		write := func(contents string) {
+			Ω(ioutil.WriteFile(path, []byte(contents), 0644)).Should(Succeed())
+		}
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "config")
+			Ω(err).Should(BeNil())
+			path = filepath.Join(dir, "config.yml")
+			write("groups: [{group: before}]")
+			atomic.StoreInt32(&loads, 0)
+
+			stop, err = srv.WatchConfigFile(path, func() (*summary.Config, error) {
+				atomic.AddInt32(&loads, 1)
+				return summary.SetupConfigFromFile(path, "", "", "", "", "")
+			})
+			Ω(err).Should(BeNil())
+		})
+
+		AfterEach(func() {
+			stop()
+			os.RemoveAll(dir)
+		})
+
+		It("reloads the config when the file changes", func() {
+			write("groups: [{group: after}]")
+			Eventually(index).Should(ContainSubstring(`/group/after`))
+		})
+
+		It("keeps the current config when the new one is invalid", func() {
+			write("groups: [{group: after, hosts: [{pipelines: []}]}]")
+			Eventually(func() int32 { return atomic.LoadInt32(&loads) }).Should(Equal(int32(1)))
+			Consistently(index).Should(ContainSubstring(`/group/before`))
+		})
+
+		It("reloads the config on SIGHUP", func() {
+			Ω(syscall.Kill(os.Getpid(), syscall.SIGHUP)).Should(Succeed())
+			Eventually(func() int32 { return atomic.LoadInt32(&loads) }).Should(BeNumerically(">=", 1))
+		})
+	})
+})
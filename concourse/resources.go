@@ -0,0 +1,52 @@
+package summary
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/concourse/atc"
+)
+
+// BrokenResource is a pipeline resource whose latest check failed
+type BrokenResource struct {
+	Name       string `json:"name"`
+	CheckError string `json:"check_error"`
+}
+
+// brokenResources returns the resources of a pipeline that are failing to check. A failing
+// resource breaks every group of its pipeline, whether or not any job uses it.
+func brokenResources(resources []atc.Resource) []atc.Resource {
+	var broken []atc.Resource
+	for _, resource := range resources {
+		if resource.FailingToCheck || resource.CheckError != "" {
+			broken = append(broken, resource)
+		}
+	}
+	return broken
+}
+
+func (d *Data) addBrokenResource(resource atc.Resource) {
+	d.BrokenResource = true
+	for _, existing := range d.BrokenResources {
+		if existing.Name == resource.Name {
+			return
+		}
+	}
+	d.BrokenResources = append(d.BrokenResources, BrokenResource{Name: resource.Name, CheckError: resource.CheckError})
+	sort.Slice(d.BrokenResources, func(i, j int) bool {
+		return d.BrokenResources[i].Name < d.BrokenResources[j].Name
+	})
+}
+
+// BrokenResourceSummary describes the broken resources of data, for display on its tile
+func (d Data) BrokenResourceSummary() string {
+	var lines []string
+	for _, resource := range d.BrokenResources {
+		if resource.CheckError == "" {
+			lines = append(lines, resource.Name+": failing to check")
+		} else {
+			lines = append(lines, resource.Name+": "+resource.CheckError)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
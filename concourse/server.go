@@ -2,6 +2,7 @@ package summary
 
 import (
 	"net/http"
+	"sync"
 
 	"github.com/gorilla/mux"
 )
@@ -9,21 +10,67 @@ import (
 // Server struct
 type Server struct {
 	Config *Config
+
+	mu sync.RWMutex
 }
 
 // CreateServer - creates a server
 func CreateServer(config *Config) *Server {
+	config.Collector = NewCollector(config)
 	return &Server{Config: config}
 }
 
-// Start - starts the web server
+// Start - starts the web server and the background collection of data from concourse
 func (s *Server) Start() *mux.Router {
+	config := s.currentConfig()
+	if config.Collector == nil {
+		config.Collector = NewCollector(config)
+	}
+	config.Collector.Start()
+
 	router := mux.NewRouter()
 
-	router.HandleFunc("/", s.Config.Index)
-	router.HandleFunc("/host/{host}", s.Config.HostSummary)
-	router.HandleFunc("/group/{group}", s.Config.GroupSummary)
+	router.HandleFunc("/", s.handle((*Config).Index))
+	router.HandleFunc("/host/{host}", s.handle((*Config).HostSummary))
+	router.HandleFunc("/host/{host}/pipeline/{pipeline}", s.handle((*Config).PipelineSummary))
+	router.HandleFunc("/group/{group}", s.handle((*Config).GroupSummary))
+	router.HandleFunc("/stale", s.handle((*Config).StaleSummary))
+	router.HandleFunc("/api/v1/host/{host}", s.handle((*Config).HostSummaryJSON))
+	router.HandleFunc("/api/v1/host/{host}/workers", s.handle((*Config).HostWorkersJSON))
+	router.HandleFunc("/api/v1/group/{group}", s.handle((*Config).GroupSummaryJSON))
+	router.HandleFunc("/api/v1/history", s.handle((*Config).HistoryJSON))
+	router.HandleFunc("/events/host/{host}", s.handle((*Config).HostEvents))
+	router.HandleFunc("/events/group/{group}", s.handle((*Config).GroupEvents))
+	router.HandleFunc("/metrics", s.handle((*Config).Metrics))
 	router.PathPrefix("/").Handler(http.FileServer(http.Dir("./assets/")))
 
 	return router
 }
+
+// Reload atomically replaces the config used to serve requests. The new config keeps the
+// templates and collector of the current one.
+func (s *Server) Reload(config *Config) {
+	current := s.currentConfig()
+	if config.Templates == nil {
+		config.Templates = current.Templates
+	}
+	config.Collector = current.Collector
+	config.Collector.Reconfigure(config)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Config = config
+}
+
+func (s *Server) currentConfig() *Config {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.Config
+}
+
+// handle serves a request using the config that is current when the request is received
+func (s *Server) handle(handler func(*Config, http.ResponseWriter, *http.Request)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handler(s.currentConfig(), w, r)
+	}
+}
@@ -0,0 +1,105 @@
+package summary
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// groupState is whether a pipeline group was failing and since when
+type groupState struct {
+	failing bool
+	since   time.Time
+}
+
+// stateTracker remembers when each pipeline group of each host last changed between failing
+// and passing
+type stateTracker struct {
+	mu    sync.Mutex
+	hosts map[string]map[string]groupState
+}
+
+func newStateTracker() *stateTracker {
+	return &stateTracker{hosts: map[string]map[string]groupState{}}
+}
+
+// track sets Since on the data of a snapshot. A pipeline group that has not been seen before is
+// seeded from the times of its latest builds, as is one that changed state since the previous
+// collection, falling back to the time of the collection.
+func (t *stateTracker) track(host string, snapshot Snapshot) {
+	if snapshot.Err != nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	previous := t.hosts[host]
+	current := make(map[string]groupState, len(snapshot.Data))
+	for i, datum := range snapshot.Data {
+		state := groupState{failing: failing(datum)}
+		last, ok := previous[datum.Key()]
+		if ok && last.failing == state.failing {
+			state.since = last.since
+		} else {
+			state.since = seedSince(datum)
+			if ok && (state.since.IsZero() || state.since.Before(last.since)) {
+				state.since = snapshot.UpdatedAt
+			}
+		}
+		current[datum.Key()] = state
+		snapshot.Data[i].Since = state.since
+	}
+	t.hosts[host] = current
+}
+
+// forget drops the states of the pipeline groups of a host
+func (t *stateTracker) forget(host string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.hosts, host)
+}
+
+// seedSince returns when a pipeline group was last known to enter its current state: for a
+// failing group, the end of the earliest of its failed or errored builds, otherwise the end of
+// its latest build. It is zero when the builds have no times.
+func seedSince(d Data) time.Time {
+	var since time.Time
+	for _, job := range d.Jobs {
+		if job.EndTime.IsZero() {
+			continue
+		}
+		if failing(d) {
+			if (job.Status == "failed" || job.Status == "errored") && (since.IsZero() || job.EndTime.Before(since)) {
+				since = job.EndTime
+			}
+		} else if job.EndTime.After(since) {
+			since = job.EndTime
+		}
+	}
+	return since
+}
+
+// TimeInState describes how long the pipeline group has been failing or passing, eg,
+// "failing for 3h 12m", or is blank when that is not known
+func (d Data) TimeInState() string {
+	if d.Since.IsZero() {
+		return ""
+	}
+	state := "green"
+	if failing(d) {
+		state = "failing"
+	}
+	return fmt.Sprintf("%s for %s", state, formatDuration(time.Since(d.Since)))
+}
+
+func formatDuration(d time.Duration) string {
+	switch {
+	case d >= 24*time.Hour:
+		return fmt.Sprintf("%dd", int(d/(24*time.Hour)))
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh %dm", int(d/time.Hour), int(d%time.Hour/time.Minute))
+	case d < 0:
+		return "0m"
+	default:
+		return fmt.Sprintf("%dm", int(d/time.Minute))
+	}
+}
@@ -0,0 +1,136 @@
+package summary_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/FidelityInternational/go-concourse-summary/concourse"
+)
+
+// timedJobsPayload returns the jobs of a pipeline whose latest builds have statuses and ended at
+// endTimes, or have no times when endTimes is nil
+func timedJobsPayload(statuses []string, endTimes []time.Time) string {
+	jobs := make([]string, len(statuses))
+	for i, status := range statuses {
+		times := ""
+		if endTimes != nil {
+			times = fmt.Sprintf(`, "start_time": %d, "end_time": %d`, endTimes[i].Add(-time.Minute).Unix(), endTimes[i].Unix())
+		}
+		jobs[i] = fmt.Sprintf(`{"id": %d, "name": "job-%d", "team_name": "main", "finished_build": {"id": %d, "name": "1", "status": "%s"%s}}`, i+1, i+1, i+1, status, times)
+	}
+	return "[" + strings.Join(jobs, ",") + "]"
+}
+
+var _ = Describe("Time in state", func() {
+	var (
+		concourse *fakeConcourse
+		config    *summary.Config
+		host      summary.Host
+		now       time.Time
+	)
+
+	setJobs := func(payload string) {
+		concourse.mock(jobsRoute("pipeline-a", payload))
+	}
+
+	collect := func() summary.Data {
+		snapshot := config.Collector.Collect(host)
+		Ω(snapshot.Err).Should(BeNil())
+		for _, datum := range snapshot.Data {
+			if datum.Pipeline == "pipeline-a" {
+				return datum
+			}
+		}
+		Fail("pipeline-a was not collected")
+		return summary.Data{}
+	}
+
+	BeforeEach(func() {
+		now = time.Now().Truncate(time.Second)
+		concourse = newFakeConcourse(pipelinesRoute(multiplePipelinesPayload))
+		concourse.mock(pipelineRoutes(buildPayload("succeeded"), "pipeline-a", "pipeline-b", "pipeline-c")...)
+		host = concourse.host("ci")
+		config = buildConfig(nil, "main", "http")
+		config.Collector = summary.NewCollector(config)
+	})
+
+	AfterEach(func() {
+		concourse.Close()
+	})
+
+	Context("when a failing pipeline is first collected", func() {
+		var datum summary.Data
+
+		BeforeEach(func() {
+			failedAt := now.Add(-3*time.Hour - 12*time.Minute - 30*time.Second)
+			setJobs(timedJobsPayload(
+				[]string{"succeeded", "failed", "errored"},
+				[]time.Time{now.Add(-time.Minute), now.Add(-time.Hour), failedAt},
+			))
+			datum = collect()
+		})
+
+		It("is failing since its earliest failed build ended", func() {
+			Ω(datum.TimeInState()).Should(Equal("failing for 3h 12m"))
+		})
+
+		It("includes the time in the JSON", func() {
+			var value struct {
+				Since time.Time `json:"since"`
+			}
+			body, err := json.Marshal(datum)
+			Ω(err).Should(BeNil())
+			Ω(json.Unmarshal(body, &value)).Should(Succeed())
+			Ω(value.Since.Equal(now.Add(-3*time.Hour - 12*time.Minute - 30*time.Second))).Should(BeTrue())
+		})
+
+		Context("and later builds fail", func() {
+			It("keeps the time it started failing", func() {
+				setJobs(timedJobsPayload([]string{"failed", "failed", "failed"}, []time.Time{now, now, now}))
+				Ω(collect().TimeInState()).Should(Equal("failing for 3h 12m"))
+			})
+		})
+
+		Context("and it is fixed", func() {
+			It("is green since the fix", func() {
+				setJobs(timedJobsPayload([]string{"succeeded", "succeeded", "succeeded"}, []time.Time{now.Add(-time.Minute), now.Add(-time.Hour), now.Add(-2 * time.Minute)}))
+				Ω(collect().TimeInState()).Should(Equal("green for 1m"))
+			})
+		})
+	})
+
+	Context("when a passing pipeline is first collected", func() {
+		It("is green since its latest build ended", func() {
+			setJobs(timedJobsPayload([]string{"succeeded", "succeeded"}, []time.Time{now.Add(-72 * time.Hour), now.Add(-50 * time.Hour)}))
+			Ω(collect().TimeInState()).Should(Equal("green for 2d"))
+		})
+	})
+
+	Context("when the builds have no times", func() {
+		BeforeEach(func() {
+			setJobs(timedJobsPayload([]string{"succeeded"}, nil))
+		})
+
+		It("does not know the time in state", func() {
+			datum := collect()
+			Ω(datum.Since.IsZero()).Should(BeTrue())
+			Ω(datum.TimeInState()).Should(BeEmpty())
+			body, err := json.Marshal(datum)
+			Ω(err).Should(BeNil())
+			Ω(string(body)).ShouldNot(ContainSubstring(`"since"`))
+		})
+
+		It("is failing since the collection that saw it fail", func() {
+			collect()
+			setJobs(timedJobsPayload([]string{"failed"}, nil))
+			datum := collect()
+			Ω(datum.Since).Should(BeTemporally("~", time.Now(), time.Second))
+			Ω(datum.TimeInState()).Should(Equal("failing for 0m"))
+		})
+	})
+})
@@ -0,0 +1,139 @@
+package summary
+
+import (
+	"net/http"
+	"sort"
+	"time"
+)
+
+// StalePipeline is a pipeline group on the stale report
+type StalePipeline struct {
+	Host string
+	Data
+}
+
+type staleStruct struct {
+	Header     headerStruct
+	StaleAfter string
+	Pipelines  []StalePipeline
+}
+
+// LastBuild returns when the most recent of the latest builds of the jobs of the pipeline group
+// ended, or started when it has no end time, or zero when none of the builds have times
+func (d Data) LastBuild() time.Time {
+	var last time.Time
+	for _, job := range d.Jobs {
+		built := job.EndTime
+		if built.IsZero() {
+			built = job.StartTime
+		}
+		if built.After(last) {
+			last = built
+		}
+	}
+	return last
+}
+
+// isStale reports whether the last build of a pipeline group that is not running is older than
+// staleAfter. Nothing is stale when staleAfter is zero.
+func (d Data) isStale(staleAfter time.Duration) bool {
+	if staleAfter <= 0 || d.Running {
+		return false
+	}
+	last := d.LastBuild()
+	return !last.IsZero() && time.Since(last) > staleAfter
+}
+
+// LastBuildDate returns when the pipeline group was last built for the stale report
+func (d Data) LastBuildDate() string {
+	return d.LastBuild().Format(timeFormat)
+}
+
+// LastBuildAge returns how long ago the pipeline group was last built, eg, "23d"
+func (d Data) LastBuildAge() string {
+	return formatDuration(time.Since(d.LastBuild()))
+}
+
+// staleAfter returns the staleness threshold of a group, which defaults to the global threshold,
+// or the global threshold for the host pages when csGroup is nil
+func (config *Config) staleAfter(csGroup *CSGroup) time.Duration {
+	value := config.StaleAfter
+	if csGroup != nil && csGroup.StaleAfter != "" {
+		value = csGroup.StaleAfter
+	}
+	staleAfter, _ := time.ParseDuration(value)
+	return staleAfter
+}
+
+// staleEnabled reports whether a staleness threshold is set globally or for any group
+func (config *Config) staleEnabled() bool {
+	if config.StaleAfter != "" {
+		return true
+	}
+	for _, csGroup := range config.CSGroups {
+		if csGroup.StaleAfter != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// staleReport returns every stale pipeline group of the configured hosts, by the global
+// threshold for hosts in HOSTS and by the threshold of each group otherwise, oldest first
+func (config *Config) staleReport() ([]StalePipeline, time.Time) {
+	var (
+		pipelines []StalePipeline
+		updatedAt time.Time
+		seen      = map[string]bool{}
+	)
+	add := func(host Host, pipelines []Pipeline, staleAfter time.Duration) []StalePipeline {
+		var stale []StalePipeline
+		if staleAfter <= 0 {
+			return stale
+		}
+		snapshot, _ := config.Collector.Store.Get(host.Name())
+		if snapshot.Err != nil || snapshot.UpdatedAt.IsZero() {
+			return stale
+		}
+		if updatedAt.IsZero() || snapshot.UpdatedAt.Before(updatedAt) {
+			updatedAt = snapshot.UpdatedAt
+		}
+		for _, datum := range filterData(filterTeams(snapshot.Data, host.teams(config)), pipelines, config.patterns) {
+			key := host.Name() + "/" + datum.Key()
+			if datum.isStale(staleAfter) && !seen[key] {
+				seen[key] = true
+				stale = append(stale, StalePipeline{Host: host.Name(), Data: datum})
+			}
+		}
+		return stale
+	}
+	for _, host := range config.Hosts {
+		pipelines = append(pipelines, add(host, nil, config.staleAfter(nil))...)
+	}
+	for i := range config.CSGroups {
+		csGroup := &config.CSGroups[i]
+		for _, host := range csGroup.Hosts {
+			pipelines = append(pipelines, add(host, host.Pipelines, config.staleAfter(csGroup))...)
+		}
+	}
+	sort.SliceStable(pipelines, func(i, j int) bool {
+		return pipelines[i].LastBuild().Before(pipelines[j].LastBuild())
+	})
+	return pipelines, updatedAt
+}
+
+// StaleSummary renders and serves a report of the stale pipeline groups of every configured host
+func (config *Config) StaleSummary(w http.ResponseWriter, r *http.Request) {
+	pipelines, updatedAt := config.staleReport()
+	err := config.Templates.ExecuteTemplate(w, "stale", staleStruct{
+		Header: headerStruct{
+			RefreshInterval: config.RefreshInterval,
+			UpdatedAt:       updatedAt,
+		},
+		StaleAfter: config.StaleAfter,
+		Pipelines:  pipelines,
+	})
+	if err != nil {
+		panic(err.Error())
+	}
+}
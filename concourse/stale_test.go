@@ -0,0 +1,130 @@
+package summary_test
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/gorilla/mux"
+
+	"github.com/FidelityInternational/go-concourse-summary/concourse"
+)
+
+var _ = Describe("Stale pipelines", func() {
+	var (
+		concourse *fakeConcourse
+		config    *summary.Config
+		router    *mux.Router
+		host      summary.Host
+		builtAt   map[string]time.Time
+	)
+
+	request := func(path string) string {
+		mockRecorder := get(router, path)
+		Ω(mockRecorder.Code).Should(Equal(200))
+		return stringMinifier(mockRecorder.Body.String())
+	}
+
+	BeforeEach(func() {
+		now := time.Now()
+		builtAt = map[string]time.Time{
+			"pipeline-a": now.Add(-30 * 24 * time.Hour),
+			"pipeline-b": now.Add(-10 * 24 * time.Hour),
+			"pipeline-c": now.Add(-time.Hour),
+		}
+		concourse = newFakeConcourse(pipelinesRoute(multiplePipelinesPayload))
+		for pipeline, built := range builtAt {
+			concourse.mock(pipelineRoutes(timedJobsPayload([]string{"succeeded"}, []time.Time{built}), pipeline)...)
+		}
+
+		host = concourse.host("ci")
+		config = pageConfig(host)
+		config.CSGroups = summary.CSGroups{
+			{
+				Group:      "recent",
+				Hosts:      []summary.Host{{URL: concourse.URL, Alias: "ci", Pipelines: []summary.Pipeline{{Name: "pipeline-*"}}}},
+				StaleAfter: "30m",
+			},
+		}
+	})
+
+	JustBeforeEach(func() {
+		router = Router(config)
+		Ω(config.Collector.Collect(host).Err).Should(BeNil())
+	})
+
+	AfterEach(func() {
+		config.Collector.Stop()
+		concourse.Close()
+	})
+
+	Context("when no threshold is set", func() {
+		BeforeEach(func() {
+			config.CSGroups[0].StaleAfter = ""
+		})
+
+		It("does not show any tile as stale", func() {
+			Ω(request("/host/ci")).ShouldNot(ContainSubstring("stale"))
+		})
+
+		It("reports no stale pipelines", func() {
+			Ω(request("/stale")).Should(ContainSubstring("<p>Nopipelinesarestale</p>"))
+		})
+
+		It("does not link to the report from the index", func() {
+			Ω(request("/")).ShouldNot(ContainSubstring(`href="/stale"`))
+		})
+	})
+
+	Context("when a global threshold is set", func() {
+		BeforeEach(func() {
+			config.StaleAfter = "336h"
+		})
+
+		It("shows pipelines not built within the threshold as stale on the host page", func() {
+			body := request("/host/ci")
+			Ω(body).Should(ContainSubstring(`<adata-key="main/pipeline-a//"href="http://127.0.0.1`))
+			Ω(body).Should(MatchRegexp(`pipeline-a//"href="[^"]*"target="_blank"class="outerstale"`))
+			Ω(body).Should(MatchRegexp(`pipeline-b//"href="[^"]*"target="_blank"class="outer"`))
+			Ω(body).Should(MatchRegexp(`pipeline-c//"href="[^"]*"target="_blank"class="outer"`))
+		})
+
+		It("uses the threshold of the group on the group page", func() {
+			body := request("/group/recent")
+			Ω(body).Should(MatchRegexp(`pipeline-a//"href="[^"]*"target="_blank"class="outerstale"`))
+			Ω(body).Should(MatchRegexp(`pipeline-b//"href="[^"]*"target="_blank"class="outerstale"`))
+			Ω(body).Should(MatchRegexp(`pipeline-c//"href="[^"]*"target="_blank"class="outerstale"`))
+		})
+
+		It("lists every stale pipeline, oldest first, with the date of its last build", func() {
+			body := request("/stale")
+			Ω(body).Should(ContainSubstring(fmt.Sprintf(`<td>%s</td><td>30d</td>`, strings.Replace(stringMinifier(builtAt["pipeline-a"].Format("2006-01-02 15:04:05 -0700")), "+", "&#43;", 1))))
+			Ω(body).Should(MatchRegexp(`>pipeline-a</a>.*>pipeline-b</a>.*>pipeline-c</a>`))
+			Ω(body).Should(ContainSubstring(`<td><ahref="/host/ci">ci</a></td>`))
+		})
+
+		It("links to the report from the index", func() {
+			Ω(request("/")).Should(ContainSubstring(`<ahref="/stale">Stalepipelines</a>`))
+		})
+	})
+
+	Context("when only a group threshold is set", func() {
+		BeforeEach(func() {
+			config.CSGroups[0].StaleAfter = "240h"
+		})
+
+		It("does not show tiles as stale on the host page", func() {
+			Ω(request("/host/ci")).ShouldNot(ContainSubstring("stale"))
+		})
+
+		It("lists the pipelines that are stale in the group", func() {
+			body := request("/stale")
+			Ω(body).Should(ContainSubstring(">pipeline-a</a>"))
+			Ω(body).Should(ContainSubstring(">pipeline-b</a>"))
+			Ω(body).ShouldNot(ContainSubstring(">pipeline-c</a>"))
+		})
+	})
+})
@@ -5,17 +5,29 @@ import (
 	"fmt"
 	"html/template"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
 )
 
-var defaultRefreshInterval = 30
+var (
+	defaultRefreshInterval = 30
+	defaultConcurrency     = 4
+)
+
+const (
+	timeFormat = "2006-01-02 15:04:05 -0700"
+	// allTeams in a host's teams shows the pipelines of every team its credentials can see
+	allTeams = "*"
+)
 
 type indexStruct struct {
 	Hosts  []Host
 	Groups CSGroups
+	Stale  bool
 }
 
 // Config - configuration object for summary
@@ -27,6 +39,22 @@ type Config struct {
 	Templates         *template.Template
 	Protocol          string
 	Team              string
+	Collector         *Collector
+	// AllowAdHocHosts allows pages to be served for hosts that are not configured
+	AllowAdHocHosts bool
+	// Notifications are sent the transitions of every pipeline group
+	Notifications []Notification
+	// History keeps the states of pipeline groups when it is set
+	History *HistoryConfig
+	// HistoryStore keeps the states of pipeline groups in place of the store named by History
+	HistoryStore HistoryStore
+	// ShowBuildStats shows the durations and age of the latest builds on tiles
+	ShowBuildStats bool
+	// StaleAfter is how long after their last build pipelines are shown as stale, never when blank
+	StaleAfter string
+
+	// patterns are the compiled patterns of the pipelines of every host
+	patterns patterns
 }
 
 // CSGroups is a collection of concourse summary groups
@@ -34,28 +62,64 @@ type CSGroups []CSGroup
 
 // CSGroup is a concourse summary group
 type CSGroup struct {
-	Group string `json:"group"`
-	Hosts []Host `json:"hosts"`
+	Group         string         `json:"group"`
+	Hosts         []Host         `json:"hosts"`
+	Notifications []Notification `json:"notifications"`
+	StaleAfter    string         `json:"stale_after"`
 }
 
-// Host is a concourse host defined within a concourse summary group
+// Host is a concourse host defined within a concourse summary group. The host is reached at URL
+// when it is set, or at the FQDN using the configured protocol.
 type Host struct {
-	FQDN      string     `json:"fqdn"`
-	Pipelines []Pipeline `json:"pipelines"`
+	FQDN        string     `json:"fqdn"`
+	URL         string     `json:"url"`
+	Alias       string     `json:"alias"`
+	Pipelines   []Pipeline `json:"pipelines"`
+	Auth        *Auth      `json:"auth"`
+	Interval    int        `json:"interval"`
+	Concurrency int        `json:"concurrency"`
+	Teams       []string   `json:"teams"`
+	TLS         *TLS       `json:"tls"`
+}
+
+// UnmarshalJSON allows a host to be given either as a plain FQDN string or as an object
+func (h *Host) UnmarshalJSON(data []byte) error {
+	var fqdn string
+	if err := json.Unmarshal(data, &fqdn); err == nil {
+		*h = Host{FQDN: fqdn}
+		return nil
+	}
+	type host Host
+	return json.Unmarshal(data, (*host)(h))
 }
 
-// Pipeline is a pipeline definted within a concourse summary group host
+// Pipeline is a pipeline definted within a concourse summary group host. The name and groups
+// may be globs, eg, app-pr-*, or regular expressions in slashes, eg, /^app-pr-[0-9]+$/, and
+// pipelines matching any of the exclude patterns are left out. Instance vars select the
+// instances of an instanced pipeline that have those vars.
 type Pipeline struct {
-	Name   string   `json:"name"`
-	Groups []string `json:"groups"`
+	Name         string       `json:"name"`
+	Team         string       `json:"team"`
+	InstanceVars InstanceVars `json:"instance_vars"`
+	Groups       []string     `json:"groups"`
+	Exclude      []string     `json:"exclude"`
 }
 
 type headerStruct struct {
 	RefreshInterval int
+	UpdatedAt       time.Time
 }
 
 func (h headerStruct) Now() string {
-	return time.Now().Format("2006-01-02 15:04:05 -0700")
+	return time.Now().Format(timeFormat)
+}
+
+// Updated returns when the data on the page was collected, or a blank string if it has not been yet
+func (h headerStruct) Updated() string {
+	if h.UpdatedAt.IsZero() {
+		return ""
+	}
+	return h.UpdatedAt.Format(timeFormat)
 }
 
 type hostStruct struct {
@@ -63,6 +127,11 @@ type hostStruct struct {
 	Header     headerStruct
 }
 
+type notFoundStruct struct {
+	Header  headerStruct
+	Message string
+}
+
 type groupStruct struct {
 	Header headerStruct
 	Groups []GroupData
@@ -70,6 +139,7 @@ type groupStruct struct {
 
 type singleHostStruct struct {
 	Statuses []Data
+	Workers  *WorkerSummary
 }
 
 // SetupConfig sets up a config object for summary, adding default values where appropriate
@@ -102,20 +172,22 @@ func SetupConfig(refreshInterval, groupsJSON, hostsJSON, skipSSLValidationString
 		return &Config{}, err
 	}
 
-	var hostsSlice []string
+	if err := validateGroups("CS_GROUPS", groups); err != nil {
+		return &Config{}, err
+	}
+
+	var hosts []Host
 
 	if hostsJSON == "" {
 		hostsJSON = "[]"
 	}
 
-	if err := json.Unmarshal([]byte(hostsJSON), &hostsSlice); err != nil {
+	if err := json.Unmarshal([]byte(hostsJSON), &hosts); err != nil {
 		return &Config{}, err
 	}
 
-	var hosts []Host
-
-	for _, host := range hostsSlice {
-		hosts = append(hosts, Host{FQDN: host})
+	if err := validateHosts("HOSTS", hosts); err != nil {
+		return &Config{}, err
 	}
 
 	var skipSSLValidation bool
@@ -127,42 +199,62 @@ func SetupConfig(refreshInterval, groupsJSON, hostsJSON, skipSSLValidationString
 		teamName = "main"
 	}
 
-	return &Config{
+	config := &Config{
 		RefreshInterval:   refreshIntervalInt,
 		CSGroups:          groups,
 		Hosts:             hosts,
 		SkipSSLValidation: skipSSLValidation,
 		Protocol:          "https",
 		Team:              teamName,
-	}, nil
+	}
+	if err := validateAliases(config, "HOSTS", "CS_GROUPS"); err != nil {
+		return &Config{}, err
+	}
+	if err := config.compilePipelines(); err != nil {
+		return &Config{}, err
+	}
+	return config, nil
 }
 
 // Index renders and serves the index page
 func (config *Config) Index(w http.ResponseWriter, r *http.Request) {
-	err := config.Templates.ExecuteTemplate(w, "index", indexStruct{Hosts: config.Hosts, Groups: config.CSGroups})
+	err := config.Templates.ExecuteTemplate(w, "index", indexStruct{Hosts: config.Hosts, Groups: config.CSGroups, Stale: config.staleEnabled()})
 	if err != nil {
 		panic(err.Error())
 	}
 }
 
-// HostSummary renders and serves the host page
+// HostSummary renders and serves the host page from the latest collected snapshot, narrowed by
+// any filters in the query
 func (config *Config) HostSummary(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	host := vars["host"]
-	values, err := getData(host, config)
+	host, ok := config.lookupHost(vars["host"])
+	if !ok {
+		config.hostNotFound(w, vars["host"])
+		return
+	}
+	filter, err := parseQueryFilter(r.URL.Query())
 	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "Invalid filter %s", err.Error())
+		return
+	}
+	config.Collector.Watch(host)
+	snapshot, _ := config.Collector.Store.Get(host.Name())
+	if snapshot.Err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
-		fmt.Fprintf(w, "Error collecting data from concourse (%s) please refer to logs for more details", host)
-		fmt.Println(err.Error())
+		fmt.Fprintf(w, "Error collecting data from concourse (%s) please refer to logs for more details", host.Name())
 		return
 	}
 
 	err = config.Templates.ExecuteTemplate(w, "host", hostStruct{
 		Header: headerStruct{
 			RefreshInterval: config.RefreshInterval,
+			UpdatedAt:       snapshot.UpdatedAt,
 		},
 		SingleHost: singleHostStruct{
-			Statuses: values,
+			Statuses: config.tiles(host.Name(), filter.apply(snapshot.Data), config.staleAfter(nil)),
+			Workers:  snapshot.Workers,
 		},
 	})
 	if err != nil {
@@ -170,27 +262,15 @@ func (config *Config) HostSummary(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// GroupSummary renders and serves the group
+// GroupSummary renders and serves the group from the latest collected snapshots
 func (config *Config) GroupSummary(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	group := vars["group"]
-	csGroup := config.CSGroups.group(group)
-
-	var groupsData []GroupData
-	for _, host := range csGroup.Hosts {
-		values, err := getData(host.FQDN, config)
-		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			fmt.Fprintf(w, "Error collecting data from concourse (%s) please refer to logs for more details", host.FQDN)
-			fmt.Println(err.Error())
-			return
-		}
-		groupsData = append(groupsData, GroupData{Host: host.FQDN, Statuses: filterData(values, host.Pipelines)})
-	}
+	groupsData, updatedAt := config.groupData(config.CSGroups.group(vars["group"]))
 
 	err := config.Templates.ExecuteTemplate(w, "group", groupStruct{
 		Header: headerStruct{
 			RefreshInterval: config.RefreshInterval,
+			UpdatedAt:       updatedAt,
 		},
 		Groups: groupsData,
 	})
@@ -200,6 +280,120 @@ func (config *Config) GroupSummary(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// HostSummaryJSON serves the data shown on the host page as JSON
+func (config *Config) HostSummaryJSON(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	host, ok := config.lookupHost(vars["host"])
+	if !ok {
+		writeJSON(w, http.StatusNotFound, errorJSON{Error: fmt.Sprintf("host %s is not configured", vars["host"])}, time.Time{})
+		return
+	}
+	filter, err := parseQueryFilter(r.URL.Query())
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorJSON{Error: err.Error()}, time.Time{})
+		return
+	}
+	config.Collector.Watch(host)
+	snapshot, _ := config.Collector.Store.Get(host.Name())
+	if snapshot.Err != nil {
+		writeJSON(w, http.StatusInternalServerError, errorJSON{Error: snapshot.Err.Error()}, snapshot.UpdatedAt)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, filter.apply(snapshot.Data), snapshot.UpdatedAt)
+}
+
+// GroupSummaryJSON serves the data shown on the group page as JSON
+func (config *Config) GroupSummaryJSON(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	csGroup := config.CSGroups.group(vars["group"])
+	if csGroup.Group == "" {
+		writeJSON(w, http.StatusNotFound, errorJSON{Error: fmt.Sprintf("group %s is not configured", vars["group"])}, time.Time{})
+		return
+	}
+
+	groupsData, updatedAt := config.groupData(csGroup)
+	if groupsData == nil {
+		groupsData = []GroupData{}
+	}
+	writeJSON(w, http.StatusOK, groupsData, updatedAt)
+}
+
+// hostNotFound serves a not found page for a host that is not configured
+func (config *Config) hostNotFound(w http.ResponseWriter, name string) {
+	w.WriteHeader(http.StatusNotFound)
+	err := config.Templates.ExecuteTemplate(w, "not_found", notFoundStruct{
+		Header:  headerStruct{RefreshInterval: config.RefreshInterval},
+		Message: fmt.Sprintf("Host %s is not configured", name),
+	})
+	if err != nil {
+		panic(err.Error())
+	}
+}
+
+type errorJSON struct {
+	Error string `json:"error"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}, updatedAt time.Time) {
+	w.Header().Set("Content-Type", "application/json")
+	if !updatedAt.IsZero() {
+		w.Header().Set("Last-Modified", updatedAt.UTC().Format(http.TimeFormat))
+	}
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		fmt.Println(err.Error())
+	}
+}
+
+// groupData returns the latest data for each host in a group, filtered to the group's pipelines,
+// along with when the least recently updated host was collected
+func (config *Config) groupData(csGroup CSGroup) ([]GroupData, time.Time) {
+	var (
+		groupsData []GroupData
+		updatedAt  time.Time
+	)
+	for _, host := range csGroup.Hosts {
+		config.Collector.Watch(host)
+		snapshot, _ := config.Collector.Store.Get(host.Name())
+		if snapshot.Err != nil {
+			// a failing host is shown as an error tile so that the rest of the group still renders
+			groupsData = append(groupsData, GroupData{Host: host.Name(), Error: snapshot.Err.Error()})
+			continue
+		}
+		if updatedAt.IsZero() || snapshot.UpdatedAt.Before(updatedAt) {
+			updatedAt = snapshot.UpdatedAt
+		}
+		statuses := filterData(filterTeams(snapshot.Data, host.teams(config)), host.Pipelines, config.patterns)
+		if statuses == nil {
+			statuses = []Data{}
+		}
+		statuses = config.tiles(host.Name(), statuses, config.staleAfter(&csGroup))
+		groupsData = append(groupsData, GroupData{Host: host.Name(), Statuses: statuses, Workers: snapshot.Workers})
+	}
+	return groupsData, updatedAt
+}
+
+// tiles prepares the data of a host to be rendered as tiles, returning a copy of data
+func (config *Config) tiles(host string, data []Data, staleAfter time.Duration) []Data {
+	data = config.Collector.history().trends(host, data)
+	stalled := 0
+	if snapshot, _ := config.Collector.Store.Get(host); snapshot.Workers != nil {
+		stalled = snapshot.Workers.Stalled()
+	}
+	if !config.ShowBuildStats && staleAfter <= 0 && stalled == 0 {
+		return data
+	}
+	tiles := make([]Data, len(data))
+	for i, datum := range data {
+		datum.ShowBuildStats = config.ShowBuildStats
+		datum.Stale = datum.isStale(staleAfter)
+		datum.StalledWorkers = stalled
+		tiles[i] = datum
+	}
+	return tiles
+}
+
 func (csGroups CSGroups) group(group string) CSGroup {
 	for _, csGroup := range csGroups {
 		if csGroup.Group == group {
@@ -208,3 +402,147 @@ func (csGroups CSGroups) group(group string) CSGroup {
 	}
 	return CSGroup{}
 }
+
+// concurrency returns how many pipelines may have their jobs fetched at once from the host
+func (h Host) concurrency() int {
+	if h.Concurrency < 1 {
+		return defaultConcurrency
+	}
+	return h.Concurrency
+}
+
+// teams returns the teams whose pipelines are shown for the host, defaulting to the configured team
+func (h Host) teams(config *Config) []string {
+	if len(h.Teams) == 0 {
+		return []string{config.Team}
+	}
+	return h.Teams
+}
+
+// Name returns the alias of the host, which identifies it in page URLs, data and metrics. It
+// defaults to the FQDN, or to the host and port of the URL.
+func (h Host) Name() string {
+	switch {
+	case h.Alias != "":
+		return h.Alias
+	case h.FQDN != "":
+		return h.FQDN
+	}
+	if u, err := url.Parse(h.URL); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return h.URL
+}
+
+// baseURL returns the URL of the concourse host, without a trailing slash
+func (h Host) baseURL(config *Config) string {
+	if h.URL != "" {
+		return strings.TrimRight(h.URL, "/")
+	}
+	return fmt.Sprintf("%s://%s", config.Protocol, h.FQDN)
+}
+
+// host returns the configured host named name, so that its settings such as auth are applied,
+// or a bare host with name as its FQDN when name has not been configured. The teams of the host
+// are those of every entry for name, so that one collection covers every group the host appears in,
+// and settings left unset by one entry are taken from another.
+func (config *Config) host(name string) Host {
+	var (
+		found  Host
+		ok     bool
+		teams  []string
+		seen   = map[string]bool{}
+		merged = func(host Host) {
+			for _, team := range host.teams(config) {
+				if !seen[team] {
+					seen[team] = true
+					teams = append(teams, team)
+				}
+			}
+		}
+	)
+	for _, host := range config.Hosts {
+		if host.Name() == name {
+			if !ok {
+				found, ok = host, true
+			}
+			found.inherit(host)
+			merged(host)
+		}
+	}
+	for _, csGroup := range config.CSGroups {
+		for _, host := range csGroup.Hosts {
+			if host.Name() == name {
+				if !ok {
+					host.Pipelines = nil
+					found, ok = host, true
+				}
+				found.inherit(host)
+				merged(host)
+			}
+		}
+	}
+	if !ok {
+		return Host{FQDN: name}
+	}
+	switch {
+	case seen[allTeams]:
+		found.Teams = []string{allTeams}
+	case len(teams) == 1 && teams[0] == config.Team:
+		found.Teams = nil
+	default:
+		found.Teams = teams
+	}
+	return found
+}
+
+// inherit sets the settings of h that are not set from another entry for the same host
+func (h *Host) inherit(other Host) {
+	if h.Auth == nil {
+		h.Auth = other.Auth
+	}
+	if h.TLS == nil {
+		h.TLS = other.TLS
+	}
+	if h.Interval == 0 {
+		h.Interval = other.Interval
+	}
+	if h.Concurrency == 0 {
+		h.Concurrency = other.Concurrency
+	}
+}
+
+// lookupHost returns the host named name, or false when it is not configured and ad hoc hosts
+// are not allowed
+func (config *Config) lookupHost(name string) (Host, bool) {
+	if config.AllowAdHocHosts {
+		return config.host(name), true
+	}
+	for _, host := range config.allHosts() {
+		if host.Name() == name {
+			return host, true
+		}
+	}
+	return Host{}, false
+}
+
+// allHosts returns every configured host once, whether defined in hosts or only within groups
+func (config *Config) allHosts() []Host {
+	var hosts []Host
+	seen := map[string]bool{}
+	for _, host := range config.Hosts {
+		if !seen[host.Name()] {
+			seen[host.Name()] = true
+			hosts = append(hosts, config.host(host.Name()))
+		}
+	}
+	for _, csGroup := range config.CSGroups {
+		for _, host := range csGroup.Hosts {
+			if !seen[host.Name()] {
+				seen[host.Name()] = true
+				hosts = append(hosts, config.host(host.Name()))
+			}
+		}
+	}
+	return hosts
+}
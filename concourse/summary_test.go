@@ -17,7 +17,7 @@ import (
 )
 
 func Router(config *summary.Config) *mux.Router {
-	server := &summary.Server{Config: config}
+	server := summary.CreateServer(config)
 	r := server.Start()
 	return r
 }
@@ -40,6 +40,11 @@ func stripDate(in string) (out string) {
 	return re.ReplaceAllString(in, "yyyy-mm-ddhh:mm:ss&#43;zzzz")
 }
 
+func stripSince(in string) (out string) {
+	re := regexp.MustCompile(`(green|failing)for[\dhmd]+<`)
+	return re.ReplaceAllString(in, "${1}forN<")
+}
+
 func stripHostPort(in string) (out string) {
 	re := regexp.MustCompile(`127\.0\.0\.1:\d{1,6}`)
 	return re.ReplaceAllString(in, "127.0.0.1:pppp")
@@ -131,6 +136,17 @@ var _ = Describe("#SetupConfig", func() {
 			})
 		})
 
+		Context("and a group is not valid", func() {
+			BeforeEach(func() {
+				groupsJSON = `[{"group": "test", "hosts": [{"pipelines": []}]}]`
+			})
+
+			It("returns an error naming the key", func() {
+				Ω(err).Should(MatchError(`CS_GROUPS[0].hosts[0].fqdn: must not be blank`))
+				Ω(config).Should(Equal(&summary.Config{}))
+			})
+		})
+
 		Context("and the JSON is valid", func() {
 			BeforeEach(func() {
 				groupsJSON = `[{"group": "test", "hosts": []}]`
@@ -225,9 +241,10 @@ var _ = Describe("#SetupConfig", func() {
 
 func buildConfig(templates *template.Template, team string, protocol string) *summary.Config {
 	config := summary.Config{
-		Templates: templates,
-		Team:      team,
-		Protocol:  protocol,
+		Templates:       templates,
+		Team:            team,
+		Protocol:        protocol,
+		AllowAdHocHosts: true,
 	}
 	return &config
 }
@@ -434,6 +451,7 @@ var _ = Describe("#HostSummary", func() {
 	)
 
 	AfterEach(func() {
+		config.Collector.Stop()
 		if server != nil {
 			teardown()
 		}
@@ -444,8 +462,36 @@ var _ = Describe("#HostSummary", func() {
 	JustBeforeEach(func() {
 		mockRecorder = httptest.NewRecorder()
 
+		router := Router(config)
+		config.Collector.Collect(summary.Host{FQDN: Host(server)})
 		req, _ := http.NewRequest("GET", fmt.Sprintf("http://example.com/host/%s", Host(server)), nil)
-		Router(config).ServeHTTP(mockRecorder, req)
+		router.ServeHTTP(mockRecorder, req)
+	})
+
+	Context("when ad hoc hosts are not allowed", func() {
+		BeforeEach(func() {
+			setupMultiple([]MockRoute{
+				{"GET", "/api/v1/teams/main/pipelines", "[]", 200, "", nil},
+			})
+			config.AllowAdHocHosts = false
+		})
+
+		Context("and the host is not configured", func() {
+			It("returns a not found page", func() {
+				Ω(mockRecorder.Code).Should(Equal(404))
+				Ω(mockRecorder.Body.String()).Should(MatchRegexp(`<h1>Host 127.0.0.1:\d{1,6} is not configured</h1>`))
+			})
+		})
+
+		Context("and the host is configured", func() {
+			BeforeEach(func() {
+				config.Hosts = []summary.Host{{FQDN: Host(server)}}
+			})
+
+			It("returns the page", func() {
+				Ω(mockRecorder.Code).Should(Equal(200))
+			})
+		})
 	})
 
 	Context("when concourse returns invalid json", func() {
@@ -486,6 +532,7 @@ var _ = Describe("#HostSummary", func() {
 	<body>
 		<div class="time">
 			2017-09-08 15:17:56 &#43;0100 (<span id="countdown">0</span>)
+			<span class="updated">updated 2017-09-13 09:38:03 &#43;0100</span>
 			<div class="right">
 				<a class="github" href="https://github.com/FidelityInternational/go-concourse-summary" target="_blank">&nbsp;</a>
 			</div>
@@ -503,11 +550,31 @@ var _ = Describe("#HostSummary", func() {
 		})
 	})
 
+	Context("and concourse has a pipeline with a broken resource", func() {
+		BeforeEach(func() {
+			mocks := []MockRoute{
+				{"GET", "/api/v1/teams/main/pipelines", examplePipeline, 200, "", nil},
+				{"GET", "/api/v1/teams/main/pipelines/cf-example-pipeline/jobs", examplePipelineJobs, 200, "", nil},
+				{"GET", "/api/v1/teams/main/pipelines/cf-example-pipeline/resources", examplePipelineBrokenResources, 200, "", nil},
+			}
+			setupMultiple(mocks)
+		})
+
+		It("shows the failing resource on the tile", func() {
+			Ω(mockRecorder.Code).Should(Equal(200))
+			body := stringMinifier(mockRecorder.Body.String())
+			Ω(body).Should(ContainSubstring(`class="outer"title="testResource1:gitclonefailed:authenticationrequiredunusedResource:failingtocheck">`))
+			Ω(body).Should(ContainSubstring(`<divclass="broken"></div>`))
+			Ω(body).Should(ContainSubstring(`<spanclass="broken_resource"><span>testResource1:gitclonefailed:authenticationrequired</span></span><spanclass="broken_resource"><span>unusedResource</span></span>`))
+		})
+	})
+
 	Context("and concourse has pipelines", func() {
 		BeforeEach(func() {
 			mocks := []MockRoute{
 				{"GET", "/api/v1/teams/main/pipelines", pipelinesPayload, 200, "", nil},
 				{"GET", "/api/v1/teams/main/pipelines/test1/jobs", jobsPayload, 200, "", nil},
+				{"GET", "/api/v1/teams/main/pipelines/test1/resources", "[]", 200, "", nil},
 			}
 			setupMultiple(mocks)
 		})
@@ -528,6 +595,7 @@ var _ = Describe("#HostSummary", func() {
 	<body>
 		<div class="time">
 			2017-09-08 17:05:56 &#43;0100 (<span id="countdown">0</span>)
+			<span class="updated">updated 2017-09-13 09:38:03 &#43;0100</span>
 			<div class="right">
 				<a class="github" href="https://github.com/FidelityInternational/go-concourse-summary" target="_blank">&nbsp;</a>
 			</div>
@@ -536,7 +604,7 @@ var _ = Describe("#HostSummary", func() {
 <div class="scalable">
 
 
-	<a href="http://127.0.0.1:49898/teams/main/pipelines/test1" target="_blank" class="outer">
+	<a data-key="main/test1//" href="http://127.0.0.1:49898/teams/main/pipelines/test1" target="_blank" class="outer">
 	<div class="status">
 		<div class="paused_job" style="width: 0%;"></div>
 		<div class="aborted" style="width: 16%;"></div>
@@ -549,6 +617,7 @@ var _ = Describe("#HostSummary", func() {
 	<div class="inner">
 		<span class="test1"><span>test1</span></span>
 		<span class=""><span></span></span>
+		<span class="team"><span>main</span></span>
 	</div>
 	</a>
 
@@ -566,14 +635,17 @@ var _ = Describe("#GroupSummary", func() {
 		templates    = template.Must(template.ParseGlob("../templates/*"))
 		mockRecorder *httptest.ResponseRecorder
 		config       = buildConfig(templates, "main", "http")
+		extraHosts   []summary.Host
 	)
 
 	AfterEach(func() {
+		config.Collector.Stop()
 		if server != nil {
 			teardown()
 		}
 
 		config = buildConfig(templates, "main", "http")
+		extraHosts = nil
 	})
 
 	JustBeforeEach(func() {
@@ -582,16 +654,20 @@ var _ = Describe("#GroupSummary", func() {
 		config.CSGroups = []summary.CSGroup{
 			{
 				Group: "test",
-				Hosts: []summary.Host{
+				Hosts: append([]summary.Host{
 					{
 						FQDN: Host(server),
 					},
-				},
+				}, extraHosts...),
 			},
 		}
 
+		router := Router(config)
+		for _, host := range config.CSGroups[0].Hosts {
+			config.Collector.Collect(host)
+		}
 		req, _ := http.NewRequest("GET", "http://example.com/group/test", nil)
-		Router(config).ServeHTTP(mockRecorder, req)
+		router.ServeHTTP(mockRecorder, req)
 	})
 
 	Context("when concourse returns invalid json", func() {
@@ -602,9 +678,77 @@ var _ = Describe("#GroupSummary", func() {
 			setupMultiple(mocks)
 		})
 
-		It("returns an error", func() {
-			Ω(mockRecorder.Code).Should(Equal(500))
-			Ω(mockRecorder.Body.String()).Should(MatchRegexp(`Error collecting data from concourse \(127.0.0.1:\d{1,6}\) please refer to logs for more details`))
+		It("returns a page with an error tile for the host", func() {
+			Ω(mockRecorder.Code).Should(Equal(200))
+			Ω(stripHostPort(stripDate(stringMinifier(mockRecorder.Body.String())))).Should(Equal(stripHostPort(stripDate(stringMinifier(`
+<!DOCTYPE html>
+<html>
+  <head rel="v2">
+    <title>Concourse Summary</title>
+    <link rel="icon" type="image/png" href="/favicon.png" sizes="32x32">
+    <link rel="stylesheet" type="text/css" href="/styles.css">
+    <script>window.refresh_interval =  0 </script>
+    <script src="/favico-0.3.10.min.js"></script>
+    <script src="/refresh.js"></script>
+  </head>
+  <body>
+    <div class="time">
+      2017-09-13 09:38:03 &#43;0100 (<span id="countdown">0</span>)
+      <span class="updated">waiting for data</span>
+      <div class="right">
+        <a class="github" href="https://github.com/FidelityInternational/go-concourse-summary" target="_blank">&nbsp;</a>
+      </div>
+    </div>
+
+
+<div class="group">
+  <a href="/host/127.0.0.1:53553">127.0.0.1:53553</a>
+  <div>
+
+    <a href="/host/127.0.0.1:53553" class="outer error" title="invalid character &#39;}&#39; looking for beginning of value">
+    <div class="inner">
+      <span><span>Error collecting data</span></span>
+      <span class="reason"><span>invalid character &#39;}&#39; looking for beginning of value</span></span>
+    </div>
+    </a>
+
+  </div>
+</div>
+
+
+  </body>
+</html>
+				`)))))
+		})
+
+		Context("and another host in the group is healthy", func() {
+			var healthyServer *httptest.Server
+
+			BeforeEach(func() {
+				healthyRouter := mux.NewRouter()
+				healthyRouter.HandleFunc("/api/v1/teams/main/pipelines", func(w http.ResponseWriter, r *http.Request) {
+					fmt.Fprint(w, pipelinesPayload)
+				})
+				healthyRouter.HandleFunc("/api/v1/teams/main/pipelines/test1/jobs", func(w http.ResponseWriter, r *http.Request) {
+					fmt.Fprint(w, jobsPayload)
+				})
+				healthyRouter.HandleFunc("/api/v1/teams/main/pipelines/test1/resources", func(w http.ResponseWriter, r *http.Request) {
+					fmt.Fprint(w, "[]")
+				})
+				healthyServer = httptest.NewServer(healthyRouter)
+				extraHosts = []summary.Host{{FQDN: Host(healthyServer)}}
+			})
+
+			AfterEach(func() {
+				healthyServer.Close()
+			})
+
+			It("renders the healthy host alongside the error tile", func() {
+				Ω(mockRecorder.Code).Should(Equal(200))
+				body := mockRecorder.Body.String()
+				Ω(body).Should(ContainSubstring(`class="outer error"`))
+				Ω(body).Should(ContainSubstring(fmt.Sprintf(`<a data-key="main/test1//" href="http://%s/teams/main/pipelines/test1" target="_blank" class="outer">`, Host(healthyServer))))
+			})
 		})
 	})
 
@@ -632,6 +776,7 @@ var _ = Describe("#GroupSummary", func() {
   <body>
     <div class="time">
       2017-09-13 09:38:03 &#43;0100 (<span id="countdown">0</span>)
+      <span class="updated">updated 2017-09-13 09:38:03 &#43;0100</span>
       <div class="right">
         <a class="github" href="https://github.com/FidelityInternational/go-concourse-summary" target="_blank">&nbsp;</a>
       </div>
@@ -659,6 +804,7 @@ var _ = Describe("#GroupSummary", func() {
 			mocks := []MockRoute{
 				{"GET", "/api/v1/teams/main/pipelines", pipelinesPayload, 200, "", nil},
 				{"GET", "/api/v1/teams/main/pipelines/test1/jobs", jobsPayload, 200, "", nil},
+				{"GET", "/api/v1/teams/main/pipelines/test1/resources", "[]", 200, "", nil},
 			}
 			setupMultiple(mocks)
 		})
@@ -679,6 +825,7 @@ var _ = Describe("#GroupSummary", func() {
   <body>
     <div class="time">
       2017-09-13 09:38:03 &#43;0100 (<span id="countdown">0</span>)
+      <span class="updated">updated 2017-09-13 09:38:03 &#43;0100</span>
       <div class="right">
         <a class="github" href="https://github.com/FidelityInternational/go-concourse-summary" target="_blank">&nbsp;</a>
       </div>
@@ -690,7 +837,7 @@ var _ = Describe("#GroupSummary", func() {
   <div>
 
 
-  <a href="http://127.0.0.1:53555/teams/main/pipelines/test1" target="_blank" class="outer">
+  <a data-key="main/test1//" href="http://127.0.0.1:53555/teams/main/pipelines/test1" target="_blank" class="outer">
   <div class="status">
     <div class="paused_job" style="width: 0%;"></div>
     <div class="aborted" style="width: 16%;"></div>
@@ -703,6 +850,7 @@ var _ = Describe("#GroupSummary", func() {
   <div class="inner">
     <span class="test1"><span>test1</span></span>
     <span class=""><span></span></span>
+    <span class="team"><span>main</span></span>
   </div>
   </a>
 
@@ -721,13 +869,14 @@ var _ = Describe("#GroupSummary", func() {
 			mocks := []MockRoute{
 				{"GET", "/api/v1/teams/main/pipelines", examplePipeline, 200, "", nil},
 				{"GET", "/api/v1/teams/main/pipelines/cf-example-pipeline/jobs", examplePipelineJobs, 200, "", nil},
+				{"GET", "/api/v1/teams/main/pipelines/cf-example-pipeline/resources", "[]", 200, "", nil},
 			}
 			setupMultiple(mocks)
 		})
 
 		It("returns a page with status etc", func() {
 			Ω(mockRecorder.Code).Should(Equal(200))
-			Ω(stripHostPort(stripDate(stringMinifier(mockRecorder.Body.String())))).Should(Equal(stripHostPort(stripDate(stringMinifier(`
+			Ω(stripSince(stripHostPort(stripDate(stringMinifier(mockRecorder.Body.String()))))).Should(Equal(stripSince(stripHostPort(stripDate(stringMinifier(`
 <!DOCTYPE html>
 <html>
   <head rel="v2">
@@ -741,6 +890,7 @@ var _ = Describe("#GroupSummary", func() {
   <body>
     <div class="time">
       2017-09-13 09:38:03 &#43;0100 (<span id="countdown">0</span>)
+      <span class="updated">updated 2017-09-13 09:38:03 &#43;0100</span>
       <div class="right">
         <a class="github" href="https://github.com/FidelityInternational/go-concourse-summary" target="_blank">&nbsp;</a>
       </div>
@@ -752,7 +902,7 @@ var _ = Describe("#GroupSummary", func() {
   <div>
 
 
-  <a href="http://127.0.0.1:53555/teams/main/pipelines/cf-example-pipeline?group=test-group" target="_blank" class="outer">
+  <a data-key="main/cf-example-pipeline//test-group" href="http://127.0.0.1:53555/teams/main/pipelines/cf-example-pipeline?group=test-group" target="_blank" class="outer">
   <div class="status">
     <div class="paused_job" style="width: 0%;"></div>
     <div class="aborted" style="width: 0%;"></div>
@@ -765,6 +915,8 @@ var _ = Describe("#GroupSummary", func() {
   <div class="inner">
     <span class="cf-example-pipeline"><span>cf-example-pipeline</span></span>
     <span class="test-group"><span>test-group</span></span>
+    <span class="team"><span>main</span></span>
+    <span class="since"><span>green for 2000d</span></span>
   </div>
   </a>
 
@@ -774,7 +926,7 @@ var _ = Describe("#GroupSummary", func() {
 
 
   </body>
-</html>`)))))
+</html>`))))))
 		})
 	})
 })
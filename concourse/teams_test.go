@@ -0,0 +1,120 @@
+package summary_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/FidelityInternational/go-concourse-summary/concourse"
+)
+
+const teamsPayload = `[
+  {"id": 1, "name": "main"},
+  {"id": 2, "name": "other"},
+  {"id": 3, "name": "secret"}
+]`
+
+var _ = Describe("Multiple teams", func() {
+	var config *summary.Config
+
+	BeforeEach(func() {
+		mocks := []MockRoute{
+			{"GET", "/api/v1/teams", teamsPayload, 200, "", nil},
+			{"GET", "/api/v1/teams/main/pipelines", pipelinesPayload, 200, "", nil},
+			{"GET", "/api/v1/teams/main/pipelines/test1/jobs", jobsPayload, 200, "", nil},
+			{"GET", "/api/v1/teams/main/pipelines/test1/resources", "[]", 200, "", nil},
+			{"GET", "/api/v1/teams/other/pipelines", examplePipeline, 200, "", nil},
+			{"GET", "/api/v1/teams/other/pipelines/cf-example-pipeline/jobs", examplePipelineJobs, 200, "", nil},
+			{"GET", "/api/v1/teams/other/pipelines/cf-example-pipeline/resources", "[]", 200, "", nil},
+			{"GET", "/api/v1/teams/secret/pipelines", "", 403, "", nil},
+		}
+		setupMultiple(mocks)
+		config = buildConfig(nil, "main", "http")
+	})
+
+	AfterEach(func() {
+		teardown()
+	})
+
+	Describe("Collector#Collect", func() {
+		var (
+			collector *summary.Collector
+			snapshot  summary.Snapshot
+			teams     []string
+		)
+
+		JustBeforeEach(func() {
+			collector = summary.NewCollector(config)
+			snapshot = collector.Collect(summary.Host{FQDN: Host(server), Teams: teams})
+		})
+
+		Context("when the host lists its teams", func() {
+			BeforeEach(func() {
+				teams = []string{"main", "other"}
+			})
+
+			It("collects the pipelines of each team", func() {
+				Ω(snapshot.Err).Should(BeNil())
+				Ω(snapshot.Data).Should(HaveLen(2))
+				Ω(snapshot.Data[0].Team).Should(Equal("main"))
+				Ω(stripHostPort(snapshot.Data[0].URL)).Should(Equal("http://127.0.0.1:pppp/teams/main/pipelines/test1"))
+				Ω(snapshot.Data[1].Team).Should(Equal("other"))
+				Ω(stripHostPort(snapshot.Data[1].URL)).Should(Equal("http://127.0.0.1:pppp/teams/other/pipelines/cf-example-pipeline?group=test-group"))
+				Ω(snapshot.Data[1].Jobs[0].Team).Should(Equal("other"))
+			})
+		})
+
+		Context("when the host shows all teams", func() {
+			BeforeEach(func() {
+				teams = []string{"*"}
+			})
+
+			It("collects the pipelines of every team that can be seen", func() {
+				Ω(snapshot.Err).Should(BeNil())
+				Ω(snapshot.Data).Should(HaveLen(2))
+				Ω(snapshot.Data[0].Team).Should(Equal("main"))
+				Ω(snapshot.Data[1].Team).Should(Equal("other"))
+			})
+		})
+
+		Context("when a listed team cannot be seen", func() {
+			BeforeEach(func() {
+				teams = []string{"main", "secret"}
+			})
+
+			It("returns an error", func() {
+				Ω(snapshot.Err).Should(MatchError("forbidden"))
+			})
+		})
+	})
+
+	Describe("/api/v1/group/{group}", func() {
+		It("shows only the teams of each group entry", func() {
+			config.Hosts = []summary.Host{{FQDN: Host(server)}}
+			config.CSGroups = summary.CSGroups{
+				{Group: "test", Hosts: []summary.Host{{FQDN: Host(server), Teams: []string{"other"}}}},
+			}
+			router := Router(config)
+			defer config.Collector.Stop()
+
+			teamsShown := func() []string {
+				mockRecorder := httptest.NewRecorder()
+				req, _ := http.NewRequest("GET", "http://example.com/api/v1/group/test", nil)
+				router.ServeHTTP(mockRecorder, req)
+				var groups []summary.GroupData
+				json.Unmarshal(mockRecorder.Body.Bytes(), &groups)
+				var teams []string
+				for _, group := range groups {
+					for _, datum := range group.Statuses {
+						teams = append(teams, datum.Team)
+					}
+				}
+				return teams
+			}
+			Eventually(teamsShown).Should(Equal([]string{"other"}))
+		})
+	})
+})
@@ -0,0 +1,98 @@
+package summary
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+)
+
+// TLS holds the TLS settings used to connect to a concourse host
+type TLS struct {
+	CACert            string `json:"ca_cert"`
+	ClientCert        string `json:"client_cert"`
+	ClientKey         string `json:"client_key"`
+	SkipSSLValidation *bool  `json:"skip_ssl_validation"`
+}
+
+// tlsConfig returns the TLS config for a host. Certificates given by the host are trusted
+// alongside the system ones, and the host can override the global SKIP_SSL_VALIDATION.
+func (h Host) tlsConfig(config *Config) (*tls.Config, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: config.SkipSSLValidation}
+	if h.TLS == nil {
+		return tlsConfig, nil
+	}
+	if h.TLS.SkipSSLValidation != nil {
+		tlsConfig.InsecureSkipVerify = *h.TLS.SkipSSLValidation
+	}
+	if h.TLS.CACert != "" {
+		pool, err := caCertPool(h.TLS.CACert)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if h.TLS.ClientCert != "" {
+		certificate, err := tls.LoadX509KeyPair(h.TLS.ClientCert, h.TLS.ClientKey)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+	return tlsConfig, nil
+}
+
+// fingerprint identifies the contents of the certificate and key files, so that a TLS config can
+// be rebuilt when the files are rotated. Files that cannot be read are left out, as reading them
+// again for the TLS config reports the error.
+func (t *TLS) fingerprint() string {
+	if t == nil {
+		return ""
+	}
+	sum := sha256.New()
+	for _, path := range []string{t.CACert, t.ClientCert, t.ClientKey} {
+		if path == "" {
+			continue
+		}
+		if contents, err := ioutil.ReadFile(path); err == nil {
+			sum.Write(contents)
+		}
+		sum.Write([]byte{0})
+	}
+	return hex.EncodeToString(sum.Sum(nil))
+}
+
+// caCertPool returns the system certificate pool with the certificates of a PEM bundle added
+func caCertPool(path string) (*x509.CertPool, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(contents) {
+		return nil, fmt.Errorf("%s contains no PEM certificates", path)
+	}
+	return pool, nil
+}
+
+func (t *TLS) validate(key string) error {
+	if (t.ClientCert == "") != (t.ClientKey == "") {
+		return ConfigError{Key: key, Message: "client_cert and client_key must both be set"}
+	}
+	if t.CACert != "" {
+		if _, err := caCertPool(t.CACert); err != nil {
+			return ConfigError{Key: key + ".ca_cert", Message: err.Error()}
+		}
+	}
+	if t.ClientCert != "" {
+		if _, err := tls.LoadX509KeyPair(t.ClientCert, t.ClientKey); err != nil {
+			return ConfigError{Key: key + ".client_cert", Message: err.Error()}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package summary_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/gorilla/mux"
+
+	"github.com/FidelityInternational/go-concourse-summary/concourse"
+)
+
+// writeCertificate writes a self signed certificate for 127.0.0.1, usable by both servers and
+// clients, and its key to dir
+func writeCertificate(dir string) (certPath, keyPath string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Ω(err).Should(BeNil())
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	Ω(err).Should(BeNil())
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	Ω(err).Should(BeNil())
+
+	certPath = filepath.Join(dir, "cert.pem")
+	keyPath = filepath.Join(dir, "key.pem")
+	Ω(ioutil.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)).Should(Succeed())
+	Ω(ioutil.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)).Should(Succeed())
+	return certPath, keyPath
+}
+
+var _ = Describe("Hosts with TLS settings", func() {
+	var (
+		dir               string
+		certPath, keyPath string
+		tlsServer         *httptest.Server
+		clientAuth        tls.ClientAuthType
+		hostTLS           *summary.TLS
+		collector         *summary.Collector
+		host              summary.Host
+		snapshot          summary.Snapshot
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "summary-tls")
+		Ω(err).Should(BeNil())
+		certPath, keyPath = writeCertificate(dir)
+		clientAuth = tls.NoClientCert
+	})
+
+	AfterEach(func() {
+		tlsServer.Close()
+		os.RemoveAll(dir)
+	})
+
+	JustBeforeEach(func() {
+		router := mux.NewRouter()
+		router.HandleFunc("/api/v1/teams/main/pipelines", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "[]")
+		})
+		certificate, err := tls.LoadX509KeyPair(certPath, keyPath)
+		Ω(err).Should(BeNil())
+		leaf, err := x509.ParseCertificate(certificate.Certificate[0])
+		Ω(err).Should(BeNil())
+		pool := x509.NewCertPool()
+		pool.AddCert(leaf)
+
+		tlsServer = httptest.NewUnstartedServer(router)
+		tlsServer.TLS = &tls.Config{Certificates: []tls.Certificate{certificate}, ClientAuth: clientAuth, ClientCAs: pool}
+		tlsServer.StartTLS()
+
+		collector = summary.NewCollector(buildConfig(nil, "main", "https"))
+		host = summary.Host{URL: tlsServer.URL, TLS: hostTLS}
+		snapshot = collector.Collect(host)
+	})
+
+	Context("when the host has no TLS settings", func() {
+		BeforeEach(func() {
+			hostTLS = nil
+		})
+
+		It("does not trust the certificate", func() {
+			Ω(snapshot.Err).ShouldNot(BeNil())
+			Ω(snapshot.Err.Error()).Should(ContainSubstring("certificate"))
+		})
+	})
+
+	Context("when the host has a CA bundle", func() {
+		BeforeEach(func() {
+			hostTLS = &summary.TLS{CACert: certPath}
+		})
+
+		It("trusts the certificate", func() {
+			Ω(snapshot.Err).Should(BeNil())
+		})
+
+		It("reads the bundle again when it is rotated", func() {
+			writeCertificate(dir)
+			Ω(collector.Collect(host).Err).ShouldNot(BeNil())
+		})
+
+		Context("and the host requires a client certificate", func() {
+			BeforeEach(func() {
+				clientAuth = tls.RequireAndVerifyClientCert
+			})
+
+			It("fails without one", func() {
+				Ω(snapshot.Err).ShouldNot(BeNil())
+			})
+
+			Context("and the host has a client certificate", func() {
+				BeforeEach(func() {
+					hostTLS = &summary.TLS{CACert: certPath, ClientCert: certPath, ClientKey: keyPath}
+				})
+
+				It("presents it", func() {
+					Ω(snapshot.Err).Should(BeNil())
+				})
+			})
+		})
+	})
+
+	Context("when the host skips SSL validation", func() {
+		BeforeEach(func() {
+			skip := true
+			hostTLS = &summary.TLS{SkipSSLValidation: &skip}
+		})
+
+		It("does not verify the certificate", func() {
+			Ω(snapshot.Err).Should(BeNil())
+		})
+	})
+})
@@ -0,0 +1,107 @@
+package summary
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/concourse/atc"
+	"github.com/gorilla/mux"
+)
+
+// workerStates are the states of workers in the order they are shown, other states follow
+var workerStates = []string{"running", "stalled", "landing", "landed", "retiring"}
+
+// WorkerSummary is the health of the workers of a host
+type WorkerSummary struct {
+	Total      int            `json:"total"`
+	States     map[string]int `json:"states"`
+	Containers int            `json:"containers"`
+	Platforms  []string       `json:"platforms"`
+}
+
+// WorkerState is the number of workers in a state
+type WorkerState struct {
+	State string
+	Count int
+}
+
+func newWorkerSummary(workers []atc.Worker) *WorkerSummary {
+	summary := &WorkerSummary{Total: len(workers), States: map[string]int{}, Platforms: []string{}}
+	platforms := map[string]bool{}
+	for _, worker := range workers {
+		summary.States[worker.State]++
+		summary.Containers += worker.ActiveContainers
+		if worker.Platform != "" && !platforms[worker.Platform] {
+			platforms[worker.Platform] = true
+			summary.Platforms = append(summary.Platforms, worker.Platform)
+		}
+	}
+	sort.Strings(summary.Platforms)
+	return summary
+}
+
+// Stalled returns the number of stalled workers
+func (s *WorkerSummary) Stalled() int {
+	return s.States["stalled"]
+}
+
+// StateCounts returns the number of workers in each state that any worker is in
+func (s *WorkerSummary) StateCounts() []WorkerState {
+	var (
+		counts []WorkerState
+		others []string
+		known  = map[string]bool{}
+	)
+	for _, state := range workerStates {
+		known[state] = true
+		if s.States[state] > 0 {
+			counts = append(counts, WorkerState{State: state, Count: s.States[state]})
+		}
+	}
+	for state := range s.States {
+		if !known[state] {
+			others = append(others, state)
+		}
+	}
+	sort.Strings(others)
+	for _, state := range others {
+		counts = append(counts, WorkerState{State: state, Count: s.States[state]})
+	}
+	return counts
+}
+
+// getWorkers fetches the workers of a host using the credentials of its first team
+func getWorkers(host Host, config *Config, metrics *Metrics) (*WorkerSummary, error) {
+	team := config.Team
+	if teams := host.teams(config); teams[0] != allTeams {
+		team = teams[0]
+	}
+	client, err := authorisedClient(host.baseURL(config), host, team, config, metrics)
+	if err != nil {
+		return nil, err
+	}
+	workers, err := client.listWorkers()
+	if err != nil {
+		return nil, err
+	}
+	return newWorkerSummary(workers), nil
+}
+
+// HostWorkersJSON serves the health of the workers of a host as JSON
+func (config *Config) HostWorkersJSON(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	host, ok := config.lookupHost(vars["host"])
+	if !ok {
+		writeJSON(w, http.StatusNotFound, errorJSON{Error: fmt.Sprintf("host %s is not configured", vars["host"])}, time.Time{})
+		return
+	}
+	config.Collector.Watch(host)
+	snapshot, _ := config.Collector.Store.Get(host.Name())
+	if snapshot.Workers == nil {
+		writeJSON(w, http.StatusNotFound, errorJSON{Error: fmt.Sprintf("workers of %s have not been collected", host.Name())}, snapshot.UpdatedAt)
+		return
+	}
+	writeJSON(w, http.StatusOK, snapshot.Workers, snapshot.UpdatedAt)
+}
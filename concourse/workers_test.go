@@ -0,0 +1,133 @@
+package summary_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/gorilla/mux"
+
+	"github.com/FidelityInternational/go-concourse-summary/concourse"
+)
+
+const workersPayload = `[
+  {"name": "linux-1", "state": "running", "platform": "linux", "active_containers": 40},
+  {"name": "linux-2", "state": "running", "platform": "linux", "active_containers": 25},
+  {"name": "linux-3", "state": "stalled", "platform": "linux", "active_containers": 10},
+  {"name": "windows-1", "state": "running", "platform": "windows", "active_containers": 3},
+  {"name": "linux-4", "state": "landing", "platform": "linux", "active_containers": 2}
+]`
+
+var _ = Describe("Workers", func() {
+	var (
+		concourse *fakeConcourse
+		config    *summary.Config
+		router    *mux.Router
+		host      summary.Host
+		auth      *summary.Auth
+	)
+
+	BeforeEach(func() {
+		auth = &summary.Auth{Token: "my-token"}
+		concourse = newFakeConcourse(
+			MockRoute{"GET", "/api/v1/info", `{"version": "7.4.0", "worker_version": "2.3"}`, 200, "", nil},
+			MockRoute{"GET", "/api/v1/workers", workersPayload, 200, "", nil},
+			pipelinesRoute(`[{"id": 1, "name": "pipeline-a", "team_name": "main"}]`),
+		)
+		concourse.mock(pipelineRoutes(buildPayload("failed"), "pipeline-a")...)
+		config = pageConfig()
+	})
+
+	JustBeforeEach(func() {
+		host = concourse.host("ci")
+		host.Auth = auth
+		config.Hosts = []summary.Host{host}
+		config.CSGroups = summary.CSGroups{{Group: "all", Hosts: []summary.Host{host}}}
+		router = Router(config)
+		Ω(config.Collector.Collect(host).Err).Should(BeNil())
+	})
+
+	AfterEach(func() {
+		config.Collector.Stop()
+		concourse.Close()
+	})
+
+	It("summarises the workers of the host", func() {
+		snapshot, _ := config.Collector.Store.Get("ci")
+		Ω(snapshot.Workers).Should(Equal(&summary.WorkerSummary{
+			Total:      5,
+			States:     map[string]int{"running": 3, "stalled": 1, "landing": 1},
+			Containers: 80,
+			Platforms:  []string{"linux", "windows"},
+		}))
+	})
+
+	It("shows the worker panel on the host page", func() {
+		body := stringMinifier(get(router, "/host/ci").Body.String())
+		Ω(body).Should(ContainSubstring(`<divclass="outerworkersstalled"><divclass="inner"><span><span>5workers</span></span>` +
+			`<spanclass="worker_staterunning"><span>3running</span></span>` +
+			`<spanclass="worker_statestalled"><span>1stalled</span></span>` +
+			`<spanclass="worker_statelanding"><span>1landing</span></span>` +
+			`<spanclass="containers"><span>80containers</span></span>` +
+			`<spanclass="platforms"><span>linux,windows</span></span></div></div>`))
+	})
+
+	It("warns of stalled workers on the tiles", func() {
+		Ω(get(router, "/host/ci").Body.String()).Should(ContainSubstring(`<div class="stalled_workers" title="1 stalled workers"></div>`))
+		Ω(get(router, "/group/all").Body.String()).Should(ContainSubstring(`<div class="stalled_workers" title="1 stalled workers"></div>`))
+	})
+
+	It("serves the workers as JSON", func() {
+		mockRecorder := get(router, "/api/v1/host/ci/workers")
+		Ω(mockRecorder.Code).Should(Equal(200))
+		Ω(mockRecorder.Body.String()).Should(MatchJSON(`{
+			"total": 5,
+			"states": {"running": 3, "stalled": 1, "landing": 1},
+			"containers": 80,
+			"platforms": ["linux", "windows"]
+		}`))
+	})
+
+	It("includes the workers in the group JSON", func() {
+		Ω(get(router, "/api/v1/group/all").Body.String()).Should(ContainSubstring(`"workers":{"total":5,`))
+	})
+
+	Context("when no workers are stalled", func() {
+		BeforeEach(func() {
+			concourse.mock(MockRoute{"GET", "/api/v1/workers", `[{"name": "linux-1", "state": "running", "platform": "linux", "active_containers": 4}]`, 200, "", nil})
+		})
+
+		It("does not warn on the tiles", func() {
+			body := get(router, "/host/ci").Body.String()
+			Ω(body).Should(ContainSubstring(`<div class="outer workers">`))
+			Ω(body).ShouldNot(ContainSubstring("stalled"))
+		})
+	})
+
+	Context("when the workers cannot be listed", func() {
+		BeforeEach(func() {
+			concourse.mock(MockRoute{"GET", "/api/v1/workers", "forbidden", 403, "", nil})
+		})
+
+		It("still shows the pipelines without the worker panel", func() {
+			body := get(router, "/host/ci").Body.String()
+			Ω(body).Should(ContainSubstring(`data-key="main/pipeline-a//"`))
+			Ω(body).ShouldNot(ContainSubstring("workers"))
+		})
+
+		It("returns not found from the workers JSON", func() {
+			mockRecorder := get(router, "/api/v1/host/ci/workers")
+			Ω(mockRecorder.Code).Should(Equal(404))
+			Ω(mockRecorder.Body.String()).Should(MatchJSON(`{"error": "workers of ci have not been collected"}`))
+		})
+	})
+	Context("when the host has no credentials", func() {
+		BeforeEach(func() {
+			auth = nil
+		})
+
+		It("does not list the workers, which needs credentials", func() {
+			Ω(concourse.requested("/api/v1/workers")).Should(Equal(0))
+			Ω(get(router, "/host/ci").Body.String()).ShouldNot(ContainSubstring("workers"))
+		})
+	})
+})
@@ -9,9 +9,10 @@ require (
 	github.com/cenkalti/backoff v2.2.1+incompatible // indirect
 	github.com/cloudfoundry/bosh-cli v6.4.1+incompatible // indirect
 	github.com/cloudfoundry/bosh-utils v0.0.262 // indirect
-	github.com/concourse/atc v0.0.0-20170905222448-443b077f1796 // indirect
+	github.com/concourse/atc v0.0.0-20170905222448-443b077f1796
 	github.com/concourse/go-concourse v0.0.0-20170802233042-c66d72ec9071
 	github.com/cppforlife/go-patch v0.2.0 // indirect
+	github.com/fsnotify/fsnotify v1.4.9
 	github.com/google/jsonapi v0.0.0-20170708005851-46d3ced04344 // indirect
 	github.com/gorilla/context v0.0.0-20160226214623-1ea25387ff6f // indirect
 	github.com/gorilla/mux v1.4.0
@@ -23,5 +24,6 @@ require (
 	github.com/onsi/gomega v1.13.0
 	github.com/peterhellberg/link v1.0.0 // indirect
 	github.com/tedsuo/rata v1.0.0 // indirect
-	github.com/vito/go-sse v0.0.0-20160212001227-fd69d275caac // indirect
+	github.com/vito/go-sse v0.0.0-20160212001227-fd69d275caac
+	gopkg.in/yaml.v2 v2.4.0
 )
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"html/template"
 	"log"
@@ -17,12 +18,35 @@ func init() {
 }
 
 func main() {
+	configFile := flag.String("config", os.Getenv("CONFIG_FILE"), "path to a YAML or JSON config file")
+	flag.Parse()
+
 	hostsJSON := os.Getenv("HOSTS")
 	groupsJSON := os.Getenv("CS_GROUPS")
 	skipSSLValidationString := os.Getenv("SKIP_SSL_VALIDATION")
 	refreshIntervalString := os.Getenv("REFRESH_INTERVAL")
 	teamName := os.Getenv("TEAM")
-	config, err := summary.SetupConfig(refreshIntervalString, groupsJSON, hostsJSON, skipSSLValidationString, teamName)
+	allowAdHocHostsString := os.Getenv("ALLOW_AD_HOC_HOSTS")
+	setupConfig := func() (*summary.Config, error) {
+		var (
+			config *summary.Config
+			err    error
+		)
+		if *configFile != "" {
+			config, err = summary.SetupConfigFromFile(*configFile, refreshIntervalString, groupsJSON, hostsJSON, skipSSLValidationString, teamName)
+		} else {
+			config, err = summary.SetupConfig(refreshIntervalString, groupsJSON, hostsJSON, skipSSLValidationString, teamName)
+		}
+		if err != nil {
+			return config, err
+		}
+		if allowAdHocHostsString != "" {
+			config.AllowAdHocHosts = allowAdHocHostsString == "true"
+		}
+		return config, nil
+	}
+
+	config, err := setupConfig()
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -31,6 +55,13 @@ func main() {
 	server := summary.CreateServer(config)
 	router := server.Start()
 
+	if *configFile != "" {
+		_, err = server.WatchConfigFile(*configFile, setupConfig)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	fmt.Println("listening on :8080")
 	log.Fatal(http.ListenAndServe(":8080", router))
 }